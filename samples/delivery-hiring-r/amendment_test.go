@@ -0,0 +1,307 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQuorumAllPartiesExtendDueDate(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	applied, err := f.contract.ExtendDueDate(f.as(applicationId), assetId, "2025-06-30T00:00:00Z")
+
+	if err != nil || applied {
+		t.Fatalf("expected one approval to not reach quorum, got %t, %v", applied, err)
+	}
+
+	if _, err := f.contract.ExtendDueDate(f.as(applicationId), assetId, "2025-06-30T00:00:00Z"); err == nil || err.Error() != "amendment ExtendDueDate already approved by this party" {
+		t.Fatalf("expected a repeated approval to be rejected, got %v", err)
+	}
+
+	applied, err = f.contract.ExtendDueDate(f.as(processId), assetId, "2025-06-30T00:00:00Z")
+
+	if err != nil || !applied {
+		t.Fatalf("expected the second approval to reach quorum, got %t, %v", applied, err)
+	}
+
+	if asset := f.asset(assetId); !asset.DueDate.Equal(time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)) || len(asset.PendingAmendments) != 0 {
+		t.Fatalf("expected the due date extended and the amendment cleared")
+	}
+}
+
+func TestExtendDueDateRejectsClosedContracts(t *testing.T) {
+	f := newFixture(t)
+
+	terminatedId := f.signed(assetRequest())
+
+	for _, id := range []string{applicationId, processId} {
+		if _, err := f.contract.Terminate(f.as(id), terminatedId, "breach"); err != nil {
+			t.Fatalf("Terminate: %s", err)
+		}
+	}
+
+	if _, err := f.contract.ExtendDueDate(f.as(applicationId), terminatedId, "2025-06-30T00:00:00Z"); err == nil || err.Error() != "contract terminated" {
+		t.Fatalf("expected extending a terminated contract to be rejected, got %v", err)
+	}
+
+	cancelledId := f.init(assetRequest())
+
+	if err := f.contract.Cancel(f.as(applicationId), cancelledId, "no longer needed"); err != nil {
+		t.Fatalf("Cancel: %s", err)
+	}
+
+	if _, err := f.contract.ExtendDueDate(f.as(applicationId), cancelledId, "2025-06-30T00:00:00Z"); err == nil || err.Error() != "contract cancelled" {
+		t.Fatalf("expected extending a cancelled contract to be rejected, got %v", err)
+	}
+
+	deletedId := f.init(assetRequest())
+
+	if err := f.contract.ArchiveAsset(f.as(processId), deletedId); err != nil {
+		t.Fatalf("ArchiveAsset: %s", err)
+	}
+
+	if _, err := f.contract.ExtendDueDate(f.as(applicationId), deletedId, "2025-06-30T00:00:00Z"); !errors.Is(err, ErrAssetNotFound) {
+		t.Fatalf("expected extending a deleted contract to be rejected, got %v", err)
+	}
+}
+func TestQuorumAllPartiesTerminate(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	applied, err := f.contract.Terminate(f.as(applicationId), assetId, "breach")
+
+	if err != nil || applied {
+		t.Fatalf("expected one approval to not reach quorum, got %t, %v", applied, err)
+	}
+
+	if _, err := f.contract.Terminate(f.as(applicationId), assetId, "breach"); err == nil || err.Error() != "amendment Terminate already approved by this party" {
+		t.Fatalf("expected a repeated approval to be rejected, got %v", err)
+	}
+
+	applied, err = f.contract.Terminate(f.as(processId), assetId, "breach")
+
+	if err != nil || !applied {
+		t.Fatalf("expected the second approval to reach quorum, got %t, %v", applied, err)
+	}
+
+	if asset := f.asset(assetId); !asset.Terminated || len(asset.PendingAmendments) != 0 {
+		t.Fatalf("expected the contract terminated and the amendment cleared")
+	}
+}
+
+func TestQuorumPolicies(t *testing.T) {
+	f := newFixture(t)
+
+	asset := &Asset{Parties: Parties{Application: Party{Id: applicationId}, Process: Party{Id: processId}}}
+
+	for _, test := range []struct {
+		quorum    string
+		approvals int
+		reached   bool
+	}{
+		{QuorumAll, 1, false},
+		{QuorumAll, 2, true},
+		{QuorumMajority, 1, false},
+		{QuorumMajority, 2, true},
+	} {
+		asset.Quorum = test.quorum
+
+		if reached := f.contract.isQuorumReached(asset, test.approvals); reached != test.reached {
+			t.Fatalf("expected %s with %d approvals to be %t, got %t", test.quorum, test.approvals, test.reached, reached)
+		}
+	}
+
+	asset.Parties.Process.Id = ""
+	asset.Quorum = QuorumMajority
+
+	if !f.contract.isQuorumReached(asset, 1) {
+		t.Fatalf("expected a majority of a single party to be one approval")
+	}
+
+	request := assetRequest()
+	request.Quorum = "UNANIMOUS"
+
+	if _, err := f.contract.Init(f.as(applicationId), request); err == nil {
+		t.Fatalf("expected an unknown quorum to be rejected")
+	}
+}
+
+func TestReplacePartyIdentity(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.init(assetRequest())
+
+	applied, err := f.contract.ReplacePartyIdentity(f.as(processId), assetId, RoleProcess, "process-id-2025")
+
+	if err != nil || applied {
+		t.Fatalf("expected the role holder alone to not replace its own identity, got %t, %v", applied, err)
+	}
+
+	if id := f.asset(assetId).Parties.Process.Id; id != processId {
+		t.Fatalf("expected the identity unchanged until the counterparty approves, got %s", id)
+	}
+
+	applied, err = f.contract.ReplacePartyIdentity(f.as(applicationId), assetId, RoleProcess, "process-id-2025")
+
+	if err != nil || !applied {
+		t.Fatalf("expected the replacement once the counterparty approves, got %t, %v", applied, err)
+	}
+
+	if id := f.asset(assetId).Parties.Process.Id; id != "process-id-2025" {
+		t.Fatalf("expected the new identity, got %s", id)
+	}
+
+	if err := f.contract.Sign(f.as("process-id-2025"), assetId, ""); err != nil {
+		t.Fatalf("expected the new identity to sign, got %s", err)
+	}
+
+	if err := f.contract.Sign(f.as(processId), assetId, ""); err == nil {
+		t.Fatalf("expected the retired identity to be rejected")
+	}
+}
+
+func TestReplacePartyIdentityByCounterpartyNeedsQuorum(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.init(assetRequest())
+
+	if _, err := f.contract.ReplacePartyIdentity(f.as(outsiderId), assetId, RoleProcess, outsiderId); err == nil {
+		t.Fatalf("expected a non-party to be rejected")
+	}
+
+	_, err := f.contract.ReplacePartyIdentity(f.as(applicationId), assetId, RoleProcess, applicationId)
+
+	if err == nil || err.Error() != "identity application-id is already a party of this contract" {
+		t.Fatalf("expected an existing party to be rejected as the new identity, got %v", err)
+	}
+
+	applied, err := f.contract.ReplacePartyIdentity(f.as(applicationId), assetId, RoleProcess, "process-id-2025")
+
+	if err != nil || applied {
+		t.Fatalf("expected the counterparty alone to not replace the identity, got %t, %v", applied, err)
+	}
+
+	if id := f.asset(assetId).Parties.Process.Id; id != processId {
+		t.Fatalf("expected the identity unchanged, got %s", id)
+	}
+
+	applied, err = f.contract.ReplacePartyIdentity(f.as(processId), assetId, RoleProcess, "process-id-2025")
+
+	if err != nil || !applied {
+		t.Fatalf("expected the replacement once both parties approve, got %t, %v", applied, err)
+	}
+}
+
+func TestWithdrawApproval(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	if _, err := f.contract.ExtendDueDate(f.as(applicationId), assetId, "2025-06-30T00:00:00Z"); err != nil {
+		t.Fatalf("ExtendDueDate: %s", err)
+	}
+
+	err := f.contract.WithdrawApproval(f.as(processId), assetId, extendDueDateOperation)
+
+	if err == nil || err.Error() != "amendment ExtendDueDate has not been approved by this party" {
+		t.Fatalf("expected a party without an approval to be rejected, got %v", err)
+	}
+
+	if err := f.contract.WithdrawApproval(f.as(applicationId), assetId, extendDueDateOperation); err != nil {
+		t.Fatalf("WithdrawApproval: %s", err)
+	}
+
+	if pending := f.asset(assetId).PendingAmendments; len(pending) != 0 {
+		t.Fatalf("expected the amendment to be withdrawn, got %+v", pending)
+	}
+
+	applied, _ := f.contract.ExtendDueDate(f.as(processId), assetId, "2025-06-30T00:00:00Z")
+
+	if applied {
+		t.Fatalf("expected the withdrawn approval to not count towards quorum")
+	}
+
+	if applied, _ = f.contract.ExtendDueDate(f.as(applicationId), assetId, "2025-06-30T00:00:00Z"); !applied {
+		t.Fatalf("expected the amendment to apply once both parties approve")
+	}
+
+	err = f.contract.WithdrawApproval(f.as(applicationId), assetId, extendDueDateOperation)
+
+	if err == nil || err.Error() != "no pending amendment found for ExtendDueDate" {
+		t.Fatalf("expected withdrawing an applied amendment to be rejected, got %v", err)
+	}
+}
+
+func TestExtendDueDateRecordsHistory(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	f.contract.ExtendDueDate(f.as(applicationId), assetId, "2025-06-30T00:00:00Z")
+
+	if history := f.asset(assetId).DueDateHistory; len(history) != 0 {
+		t.Fatalf("expected no history before the amendment is applied, got %v", history)
+	}
+
+	f.advance(time.Hour)
+
+	if applied, err := f.contract.ExtendDueDate(f.as(processId), assetId, "2025-06-30T00:00:00Z"); err != nil || !applied {
+		t.Fatalf("expected the extension to be applied, got %t, %v", applied, err)
+	}
+
+	history := f.asset(assetId).DueDateHistory
+
+	if len(history) != 1 {
+		t.Fatalf("expected a single history entry, got %d", len(history))
+	}
+
+	change := history[0]
+
+	if !change.Old.Equal(time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)) || !change.New.Equal(time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the previous and new due dates, got %+v", change)
+	}
+
+	if change.ChangedBy != processId || !change.ChangedAt.Equal(f.now) {
+		t.Fatalf("expected the change by %s at %s, got %+v", processId, f.now, change)
+	}
+}
+
+func TestGetPendingApprovals(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	if pending, err := f.contract.GetPendingApprovals(f.as(applicationId), assetId); err != nil || len(pending) != 0 {
+		t.Fatalf("expected nothing pending, got %v, %v", pending, err)
+	}
+
+	f.contract.ExtendDueDate(f.as(applicationId), assetId, "2025-06-30T00:00:00Z")
+
+	for _, id := range []string{applicationId, processId} {
+		f.contract.Terminate(f.as(id), assetId, "breach")
+	}
+
+	pending, err := f.contract.GetPendingApprovals(f.as(processId), assetId)
+
+	if err != nil {
+		t.Fatalf("GetPendingApprovals: %s", err)
+	}
+
+	if len(pending) != 1 {
+		t.Fatalf("expected only the partially approved extension, got %+v", pending)
+	}
+
+	approval := pending[0]
+
+	if approval.Operation != extendDueDateOperation || approval.Value != "2025-06-30T00:00:00Z" || !approval.CreatedAt.Equal(f.now) {
+		t.Fatalf("expected the extension to 2025-06-30, got %+v", approval)
+	}
+
+	if len(approval.ApprovedBy) != 1 || approval.ApprovedBy[0] != applicationId || len(approval.AwaitingBy) != 1 || approval.AwaitingBy[0] != processId {
+		t.Fatalf("expected the application to have approved and the process to be awaited, got %+v", approval)
+	}
+}
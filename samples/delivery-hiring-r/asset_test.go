@@ -0,0 +1,333 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAssetExists(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.init(assetRequest())
+
+	exists, err := f.contract.AssetExists(f.as(applicationId), assetId)
+
+	if err != nil || !exists {
+		t.Fatalf("expected the asset to exist, got %t, %v", exists, err)
+	}
+
+	exists, err = f.contract.AssetExists(f.as(applicationId), "missing")
+
+	if err != nil || exists {
+		t.Fatalf("expected a missing asset to not exist, got %t, %v", exists, err)
+	}
+}
+
+func TestPutStateErrorIsReturned(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.init(assetRequest())
+
+	f.stub.putStateErr = errors.New("disk full")
+
+	err := f.contract.Sign(f.as(applicationId), assetId, "")
+
+	if err == nil || err.Error() != "failed to put to world state: disk full" {
+		t.Fatalf("expected the PutState error to be returned, got %v", err)
+	}
+
+	if _, err := f.contract.Init(f.as(applicationId), assetRequest()); err == nil {
+		t.Fatalf("expected Init to fail when the write fails")
+	}
+
+	f.stub.putStateErr = nil
+
+	if f.asset(assetId).Parties.Application.IsSigned {
+		t.Fatalf("expected the failed write to leave the asset unsigned")
+	}
+}
+
+func TestGetAssetJSONReturnsStoredBytes(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	assetJSON, err := f.contract.GetAssetJSON(f.as(applicationId), assetId)
+
+	if err != nil {
+		t.Fatalf("GetAssetJSON: %s", err)
+	}
+
+	if assetJSON != string(f.stub.State[assetId]) {
+		t.Fatalf("expected the stored bytes unchanged")
+	}
+
+	if _, err := f.contract.GetAssetJSON(f.as(applicationId), "missing"); !errors.Is(err, ErrAssetNotFound) {
+		t.Fatalf("expected ErrAssetNotFound, got %v", err)
+	}
+}
+
+// putLegacyAsset stores assetJSON as written by an older version of the contract.
+func (f *fixture) putLegacyAsset(assetId string, assetJSON string) {
+	f.t.Helper()
+
+	f.as(applicationId)
+
+	if err := f.stub.PutState(assetId, []byte(assetJSON)); err != nil {
+		f.t.Fatalf("PutState: %s", err)
+	}
+}
+
+const legacyAssetJSON = `{
+	"id": "legacy",
+	"parties": {
+		"application": {"id": "application-id", "name": "Delivery App", "isSigned": true},
+		"process": {"id": "process-id", "name": "Integration Process", "isSigned": true}
+	},
+	"beginDate": "2024-01-01T00:00:00Z",
+	"dueDate": "2024-12-31T00:00:00Z",
+	"isSigned": true,
+	"gracePeriodSeconds": -5,
+	"rightRequestDelivery": {
+		"rightRequestDeliveryMaxNumberOfOperation0": {"max": 3, "timeUnit": "MINUTE"}
+	}
+}`
+
+func TestQueryAssetMigratesLegacyAsset(t *testing.T) {
+	f := newFixture(t)
+
+	f.putLegacyAsset("legacy", legacyAssetJSON)
+
+	asset := f.asset("legacy")
+
+	if asset.SchemaVersion != currentSchemaVersion {
+		t.Fatalf("expected schema version %d, got %d", currentSchemaVersion, asset.SchemaVersion)
+	}
+
+	if asset.Requests == nil || asset.GracePeriodSeconds != 0 {
+		t.Fatalf("expected the requests map and grace period to be defaulted, got %v, %d", asset.Requests, asset.GracePeriodSeconds)
+	}
+
+	clause := asset.RightRequestDelivery
+
+	if clause.RequiredWeight != 100 || clause.MaxNumberOfAddresses != 1 || clause.ScoreThreshold != 1 {
+		t.Fatalf("expected the clause defaults, got %+v", clause)
+	}
+}
+
+func TestClauseOnAssetWithoutRequestsMap(t *testing.T) {
+	f := newFixture(t)
+
+	f.putLegacyAsset("legacy", legacyAssetJSON)
+
+	result, err := f.contract.ClauseRightRequestDelivery(f.as(processId), "legacy", validArgs())
+
+	if err != nil || !result.Valid {
+		t.Fatalf("expected the clause to run on an asset without a requests map, got %+v, %v", result, err)
+	}
+
+	requests, _ := f.contract.GetAllRequests(f.as(processId), "legacy")
+
+	if len(requests) != 1 {
+		t.Fatalf("expected the request to be recorded, got %d", len(requests))
+	}
+}
+
+func TestQueryAssetErrors(t *testing.T) {
+	f := newFixture(t)
+
+	_, err := f.contract.QueryAsset(f.as(applicationId), "missing")
+
+	if !errors.Is(err, ErrAssetNotFound) || !strings.Contains(err.Error(), "missing") {
+		t.Fatalf("expected ErrAssetNotFound with the id, got %v", err)
+	}
+
+	assetId := f.init(assetRequest())
+
+	f.stub.getStateErr = errors.New("connection reset")
+
+	_, err = f.contract.QueryAsset(f.as(applicationId), assetId)
+
+	if err == nil || errors.Is(err, ErrAssetNotFound) || err.Error() != "failed to read from state: connection reset" {
+		t.Fatalf("expected a read failure distinct from not found, got %v", err)
+	}
+}
+
+func TestQueryAssetCachesReadsWithinTransaction(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.init(assetRequest())
+
+	f.stub.reads = nil
+
+	ctx := f.as(applicationId)
+
+	for i := 0; i < 3; i++ {
+		if _, err := f.contract.QueryAsset(ctx, assetId); err != nil {
+			t.Fatalf("QueryAsset: %s", err)
+		}
+	}
+
+	if reads := f.stub.reads[assetId]; reads != 1 {
+		t.Fatalf("expected repeated reads in one transaction to hit the state once, got %d", reads)
+	}
+
+	asset, _ := f.contract.QueryAsset(ctx, assetId)
+	asset.GracePeriodSeconds = 3600
+
+	if err := f.contract.putState(ctx, assetId, asset); err != nil {
+		t.Fatalf("putState: %s", err)
+	}
+
+	reread, _ := f.contract.QueryAsset(ctx, assetId)
+
+	if reads := f.stub.reads[assetId]; reads != 2 || reread.GracePeriodSeconds != 3600 {
+		t.Fatalf("expected a write to invalidate the cached asset, got %d reads and %d", reads, reread.GracePeriodSeconds)
+	}
+
+	f.contract.QueryAsset(f.as(applicationId), assetId)
+
+	if reads := f.stub.reads[assetId]; reads != 3 {
+		t.Fatalf("expected a new transaction to read the state again, got %d", reads)
+	}
+}
+
+func TestQueryAssetReportsCorruptAsset(t *testing.T) {
+	f := newFixture(t)
+
+	for assetId, assetJSON := range map[string]string{
+		"truncated":    `{"id": "truncated", "parties": {`,
+		"incompatible": `{"id": "incompatible", "isSigned": "yes"}`,
+	} {
+		f.putLegacyAsset(assetId, assetJSON)
+
+		_, err := f.contract.QueryAsset(f.as(applicationId), assetId)
+
+		if !errors.Is(err, ErrCorruptAsset) || errors.Is(err, ErrAssetNotFound) {
+			t.Fatalf("expected ErrCorruptAsset for %s, got %v", assetId, err)
+		}
+
+		if !strings.HasPrefix(err.Error(), "corrupt asset: "+assetId+": ") {
+			t.Fatalf("expected the id and the underlying message, got %v", err)
+		}
+	}
+}
+
+func TestArchivedAssetIsHiddenButRetrievable(t *testing.T) {
+	f := newFixture(t)
+
+	archivedId := f.init(assetRequest())
+	keptId := f.init(assetRequest())
+
+	if err := f.contract.ArchiveAsset(f.as(outsiderId), archivedId); err == nil {
+		t.Fatalf("expected a non-party to be rejected")
+	}
+
+	f.advance(time.Hour)
+
+	if err := f.contract.ArchiveAsset(f.as(processId), archivedId); err != nil {
+		t.Fatalf("ArchiveAsset: %s", err)
+	}
+
+	if f.stub.State[archivedId] == nil {
+		t.Fatalf("expected the archived asset to be kept in the state")
+	}
+
+	assets, _ := f.contract.GetAllAssets(f.as(applicationId), false)
+
+	if len(assets) != 1 || assets[0].Id != keptId {
+		t.Fatalf("expected only the kept asset by default, got %d assets", len(assets))
+	}
+
+	if assets, _ := f.contract.GetAllAssets(f.as(applicationId), true); len(assets) != 2 {
+		t.Fatalf("expected both assets when including deleted ones, got %d", len(assets))
+	}
+
+	if _, err := f.contract.QueryAsset(f.as(applicationId), archivedId); !errors.Is(err, ErrAssetNotFound) {
+		t.Fatalf("expected QueryAsset to hide the archived asset, got %v", err)
+	}
+
+	archived, err := f.contract.GetAsset(f.as(applicationId), archivedId, true)
+
+	if err != nil {
+		t.Fatalf("GetAsset: %s", err)
+	}
+
+	if !archived.Deleted || archived.DeletedBy != processId || !archived.DeletedAt.Equal(f.now) {
+		t.Fatalf("expected the tombstone fields, got %t, %s, %s", archived.Deleted, archived.DeletedBy, archived.DeletedAt)
+	}
+
+	if _, err := f.contract.GetAsset(f.as(applicationId), archivedId, false); !errors.Is(err, ErrAssetNotFound) {
+		t.Fatalf("expected GetAsset without the flag to hide the archived asset, got %v", err)
+	}
+}
+
+func TestRestrictReadsToParties(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+	f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	if _, err := f.contract.QueryAsset(f.as(outsiderId), assetId); err != nil {
+		t.Fatalf("expected public reads by default, got %s", err)
+	}
+
+	restrictReads = true
+	t.Cleanup(func() { restrictReads = false })
+
+	for _, id := range []string{applicationId, processId} {
+		if _, err := f.contract.QueryAsset(f.as(id), assetId); err != nil {
+			t.Fatalf("expected %s to read the asset, got %s", id, err)
+		}
+	}
+
+	if _, err := f.contract.QueryAsset(f.as(outsiderId), assetId); err == nil || err.Error() != "only the process or the application can execute this operation" {
+		t.Fatalf("expected a non-party read to be rejected, got %v", err)
+	}
+
+	if _, err := f.contract.GetAssetJSON(f.as(outsiderId), assetId); err == nil {
+		t.Fatalf("expected the raw bytes to be guarded too")
+	}
+
+	if _, err := f.contract.GetRequestsByAsset(f.as(outsiderId), assetId); err == nil {
+		t.Fatalf("expected the request index to be guarded too")
+	}
+
+	if assets, _ := f.contract.GetAllAssets(f.as(outsiderId), false); len(assets) != 0 {
+		t.Fatalf("expected range scans to skip assets the caller is not party to, got %d", len(assets))
+	}
+}
+
+func TestDocumentHashIsImmutableAfterSigning(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.DocumentHash = "sha256:draft"
+	request.DocumentURI = "https://example.com/contract-draft.pdf"
+
+	assetId := f.init(request)
+
+	if asset := f.asset(assetId); asset.DocumentHash != "sha256:draft" || asset.DocumentURI != "https://example.com/contract-draft.pdf" {
+		t.Fatalf("expected the document set at Init, got %q, %q", asset.DocumentHash, asset.DocumentURI)
+	}
+
+	if err := f.contract.SetDocument(f.as(processId), assetId, "sha256:final", "https://example.com/contract.pdf"); err != nil {
+		t.Fatalf("expected the document to change before signing, got %s", err)
+	}
+
+	for _, id := range []string{applicationId, processId} {
+		f.contract.Sign(f.as(id), assetId, "")
+	}
+
+	err := f.contract.SetDocument(f.as(applicationId), assetId, "sha256:other", "https://example.com/other.pdf")
+
+	if err == nil || err.Error() != "cannot modify document after signing" {
+		t.Fatalf("expected the document to be immutable after signing, got %v", err)
+	}
+
+	if asset := f.asset(assetId); asset.DocumentHash != "sha256:final" || asset.DocumentURI != "https://example.com/contract.pdf" {
+		t.Fatalf("expected the signed document unchanged, got %q, %q", asset.DocumentHash, asset.DocumentURI)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestClausesRejectUnsignedAsset calls every Clause method the contract exposes, so a clause
+// added without the canExecuteClause guard fails here.
+func TestClausesRejectUnsignedAsset(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.init(assetRequest())
+
+	contract := reflect.ValueOf(f.contract)
+	clauses := 0
+
+	for i := 0; i < contract.NumMethod(); i++ {
+		method := contract.Type().Method(i)
+
+		if !strings.HasPrefix(method.Name, "Clause") {
+			continue
+		}
+
+		clauses++
+
+		args := reflect.New(method.Type.In(3)).Elem()
+		results := contract.Method(i).Call([]reflect.Value{reflect.ValueOf(f.as(applicationId)), reflect.ValueOf(assetId), args})
+
+		err, _ := results[len(results)-1].Interface().(error)
+
+		if err == nil || err.Error() != "asset is not signed" {
+			t.Errorf("expected %s to reject an unsigned asset, got %v", method.Name, err)
+		}
+	}
+
+	if clauses == 0 {
+		t.Fatalf("expected the contract to expose at least one clause")
+	}
+}
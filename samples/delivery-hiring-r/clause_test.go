@@ -0,0 +1,927 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeUnitValidation(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.RightRequestDelivery.TimeUnit = "HOUR"
+
+	assetId := f.init(request)
+
+	if unit := f.asset(assetId).RightRequestDelivery.RightRequestDeliveryMaxNumberOfOperation0.TimeUnit; unit != "HOUR" {
+		t.Fatalf("expected the time unit HOUR, got %s", unit)
+	}
+
+	request.RightRequestDelivery.TimeUnit = "FORTNIGHT"
+
+	_, err := f.contract.Init(f.as(applicationId), request)
+
+	if err == nil || err.Error() != "unsupported time unit: FORTNIGHT, expected one of SECOND/MINUTE/HOUR/DAY/WEEK/MONTH" {
+		t.Fatalf("expected a bogus time unit to be rejected, got %v", err)
+	}
+
+	err = f.contract.UpdateClauseConfig(f.as(applicationId), assetId, "RightRequestDelivery", `{"timeUnit":"fortnight"}`)
+
+	if err == nil || !strings.Contains(err.Error(), "unsupported time unit: fortnight") {
+		t.Fatalf("expected UpdateClauseConfig to reject a bogus time unit, got %v", err)
+	}
+}
+
+func TestClientRequestIdMakesTheClauseIdempotent(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	args := validArgs()
+	args.ClientRequestId = "retry-1"
+
+	first, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, args)
+
+	if err != nil {
+		t.Fatalf("ClauseRightRequestDelivery: %s", err)
+	}
+
+	if first.RequestId != "retry-1" {
+		t.Fatalf("expected the request to be recorded under the client request id, got %s", first.RequestId)
+	}
+
+	second, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, args)
+
+	if err != nil {
+		t.Fatalf("ClauseRightRequestDelivery: %s", err)
+	}
+
+	if second.RequestId != first.RequestId || second.Valid != first.Valid || second.Score != first.Score {
+		t.Fatalf("expected the cached result %+v, got %+v", first, second)
+	}
+
+	requests, _ := f.contract.GetRequestsByAsset(f.as(processId), assetId)
+
+	if len(requests) != 1 {
+		t.Fatalf("expected the retry to not record a new request, got %d", len(requests))
+	}
+
+	remaining, _ := f.contract.GetRemainingOperations(f.as(processId), assetId, "RightRequestDelivery")
+
+	if remaining.Remaining != 2 {
+		t.Fatalf("expected the retry to not use an operation, got %d remaining", remaining.Remaining)
+	}
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.as(applicationId), assetId, args); err == nil {
+		t.Fatalf("expected another client reusing the id to be rejected")
+	}
+}
+
+func TestValidationResultReportsEveryFailedRule(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	result, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, RightRequestDeliveryArgs{NumberOfAddresses: 2, Weight: 50, ProductValue: defaultMaxProductValue})
+
+	if err != nil {
+		t.Fatalf("ClauseRightRequestDelivery: %s", err)
+	}
+
+	if result.Valid || strings.Join(result.FailedRules, ",") != "numberOfAddresses,weight,productValue" {
+		t.Fatalf("expected all three rules to fail, got %+v", result)
+	}
+
+	if result.RequestId == "" {
+		t.Fatalf("expected the failed request to be recorded")
+	}
+
+	result, _ = f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, RightRequestDeliveryArgs{NumberOfAddresses: 1, Weight: 50, ProductValue: 100})
+
+	if result.Valid || strings.Join(result.FailedRules, ",") != "weight" {
+		t.Fatalf("expected only the weight rule to fail, got %+v", result)
+	}
+}
+
+func TestExecuteClause(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	result, err := f.contract.ExecuteClause(f.as(processId), assetId, "RightRequestDelivery", `{"numberOfAddresses":1,"weight":100,"productValue":100}`)
+
+	if err != nil || !result.Valid || result.RequestId == "" {
+		t.Fatalf("expected the call to be dispatched to RightRequestDelivery, got %+v, %v", result, err)
+	}
+
+	_, err = f.contract.ExecuteClause(f.as(processId), assetId, "RightRequestPickup", `{}`)
+
+	if err == nil || err.Error() != "unknown clause: RightRequestPickup" {
+		t.Fatalf("expected an unknown clause to be rejected, got %v", err)
+	}
+
+	_, err = f.contract.ExecuteClause(f.as(processId), assetId, "RightRequestDelivery", `{"numberOfAdresses":1}`)
+
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid arguments for clause RightRequestDelivery") {
+		t.Fatalf("expected a misspelled argument to be rejected, got %v", err)
+	}
+}
+
+func TestMaxProductValuePerParty(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.Parties.Application.MaxProductValue = 50000
+
+	assetId := f.signed(request)
+
+	args := validArgs()
+	args.ProductValue = 30000
+
+	result, err := f.contract.ClauseRightRequestDelivery(f.as(applicationId), assetId, args)
+
+	if err != nil || !result.Valid {
+		t.Fatalf("expected the value to pass under the application's ceiling, got %+v, %v", result, err)
+	}
+
+	result, err = f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, args)
+
+	if err != nil || result.Valid || strings.Join(result.FailedRules, ",") != "productValue" {
+		t.Fatalf("expected the value to fail under the process's default ceiling, got %+v, %v", result, err)
+	}
+
+	request.Parties.Process.MaxProductValue = -1
+
+	if _, err := f.contract.Init(f.as(applicationId), request); err == nil {
+		t.Fatalf("expected a negative ceiling to be rejected")
+	}
+}
+
+func TestValidateRightRequestDeliveryLeavesStateUntouched(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	before := map[string]string{}
+
+	for key, value := range f.stub.State {
+		before[key] = string(value)
+	}
+
+	result, err := f.contract.ValidateRightRequestDelivery(f.as(processId), assetId, RightRequestDeliveryArgs{NumberOfAddresses: 1, Weight: 50, ProductValue: 100})
+
+	if err != nil {
+		t.Fatalf("ValidateRightRequestDelivery: %s", err)
+	}
+
+	if result.Valid || strings.Join(result.FailedRules, ",") != "weight" || result.RequestId != "" {
+		t.Fatalf("expected the weight rule to fail without a recorded request, got %+v", result)
+	}
+
+	if len(f.stub.State) != len(before) {
+		t.Fatalf("expected the dry run to write nothing, got %d keys instead of %d", len(f.stub.State), len(before))
+	}
+
+	for key, value := range f.stub.State {
+		if before[key] != string(value) {
+			t.Fatalf("expected the dry run to leave %q untouched", key)
+		}
+	}
+
+	unsignedId := f.init(assetRequest())
+
+	if _, err := f.contract.ValidateRightRequestDelivery(f.as(processId), unsignedId, validArgs()); err == nil {
+		t.Fatalf("expected the dry run to apply the clause guards")
+	}
+}
+
+func TestMinIntervalBetweenCalls(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.RightRequestDelivery.MinIntervalSeconds = 30
+
+	assetId := f.signed(request)
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs()); err != nil {
+		t.Fatalf("ClauseRightRequestDelivery: %s", err)
+	}
+
+	f.advance(10 * time.Second)
+
+	_, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	if err == nil || err.Error() != "operation too frequent" {
+		t.Fatalf("expected a back-to-back call to be rejected, got %v", err)
+	}
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.as(applicationId), assetId, validArgs()); err != nil {
+		t.Fatalf("expected the interval to be tracked per client, got %s", err)
+	}
+
+	f.advance(20 * time.Second)
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs()); err != nil {
+		t.Fatalf("expected a spaced call to succeed, got %s", err)
+	}
+}
+
+func TestWeightTolerance(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.RightRequestDelivery.WeightTolerance = 5
+	request.RightRequestDelivery.MaxOperations = 10
+
+	assetId := f.signed(request)
+
+	for weight, valid := range map[int]bool{94: false, 95: true, 100: true, 105: true, 106: false} {
+		args := validArgs()
+		args.Weight = weight
+
+		result, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, args)
+
+		if err != nil {
+			t.Fatalf("ClauseRightRequestDelivery: %s", err)
+		}
+
+		if result.Valid != valid {
+			t.Fatalf("expected weight %d to be valid=%t, got %+v", weight, valid, result)
+		}
+	}
+
+	request.RightRequestDelivery.WeightTolerance = -1
+
+	if _, err := f.contract.Init(f.as(applicationId), request); err == nil {
+		t.Fatalf("expected a negative tolerance to be rejected")
+	}
+}
+
+func TestGetRemainingOperations(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	remaining := func() *RemainingOperations {
+		t.Helper()
+
+		remaining, err := f.contract.GetRemainingOperations(f.as(processId), assetId, "RightRequestDelivery")
+
+		if err != nil {
+			t.Fatalf("GetRemainingOperations: %s", err)
+		}
+
+		return remaining
+	}
+
+	if got := remaining(); !got.WindowExpired {
+		t.Fatalf("expected no open window before the first call, got %+v", got)
+	}
+
+	for i := 0; i < 2; i++ {
+		f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+	}
+
+	if got := remaining(); got.Remaining != 1 || got.WindowExpired {
+		t.Fatalf("expected 1 operation left, got %+v", got)
+	}
+
+	f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	if got := remaining(); got.Remaining != 0 || got.WindowExpired {
+		t.Fatalf("expected the cap to be reached, got %+v", got)
+	}
+
+	f.advance(61 * time.Second)
+
+	if got := remaining(); got.Remaining != 0 || !got.WindowExpired {
+		t.Fatalf("expected the window to have expired, got %+v", got)
+	}
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs()); err != nil {
+		t.Fatalf("expected the next call to open a new window, got %s", err)
+	}
+
+	if got := remaining(); got.Remaining != 2 {
+		t.Fatalf("expected 2 operations left in the new window, got %+v", got)
+	}
+
+	if _, err := f.contract.GetRemainingOperations(f.as(processId), assetId, "RequestCancellation"); err == nil {
+		t.Fatalf("expected a clause without a limit to be rejected")
+	}
+}
+
+func TestCurrencyMustMatch(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.RightRequestDelivery.Currency = "BRL"
+
+	assetId := f.signed(request)
+
+	args := validArgs()
+	args.Currency = "BRL"
+
+	if result, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, args); err != nil || !result.Valid {
+		t.Fatalf("expected a matching currency to pass, got %+v, %v", result, err)
+	}
+
+	for _, currency := range []string{"USD", ""} {
+		args.Currency = currency
+
+		_, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, args)
+
+		if err == nil || err.Error() != "currency mismatch: expected BRL, got "+currency {
+			t.Fatalf("expected the currency %q to be rejected, got %v", currency, err)
+		}
+	}
+
+	unpricedId := f.signed(assetRequest())
+
+	args.Currency = "USD"
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.as(processId), unpricedId, args); err != nil {
+		t.Fatalf("expected any currency when the clause sets none, got %s", err)
+	}
+}
+
+func TestMaxNumberOfAddresses(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.RightRequestDelivery.MaxNumberOfAddresses = 3
+	request.RightRequestDelivery.MaxOperations = 10
+
+	assetId := f.signed(request)
+
+	args := validArgs()
+	args.NumberOfAddresses = 0
+
+	_, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, args)
+
+	if err == nil || err.Error() != "number of addresses must be at least 1" {
+		t.Fatalf("expected zero addresses to be rejected, got %v", err)
+	}
+
+	for addresses, valid := range map[int]bool{1: true, 3: true, 4: false} {
+		args.NumberOfAddresses = addresses
+
+		result, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, args)
+
+		if err != nil || result.Valid != valid {
+			t.Fatalf("expected %d addresses to be valid=%t, got %+v, %v", addresses, valid, result, err)
+		}
+	}
+}
+
+type capturingLogger struct {
+	lines []string
+}
+
+func (l *capturingLogger) Infof(format string, args ...interface{}) {
+	l.lines = append(l.lines, "INFO "+fmt.Sprintf(format, args...))
+}
+
+func (l *capturingLogger) Errorf(format string, args ...interface{}) {
+	l.lines = append(l.lines, "ERROR "+fmt.Sprintf(format, args...))
+}
+
+func TestClauseLogsThroughLogger(t *testing.T) {
+	f := newFixture(t)
+
+	captured := new(capturingLogger)
+
+	logger = captured
+	t.Cleanup(func() { logger = noopLogger{} })
+
+	assetId := f.signed(assetRequest())
+
+	result, _ := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, RightRequestDeliveryArgs{NumberOfAddresses: 1, Weight: 50, ProductValue: 100})
+
+	f.contract.ClauseRightRequestDelivery(f.as(outsiderId), assetId, validArgs())
+
+	expected := []string{
+		"INFO RightRequestDelivery: executing for asset " + assetId,
+		fmt.Sprintf("INFO RightRequestDelivery: asset %s request %s valid=false failedRules=[weight]", assetId, result.RequestId),
+		"INFO RightRequestDelivery: executing for asset " + assetId,
+		"ERROR RightRequestDelivery: asset " + assetId + ": only the process or the application can execute this operation",
+	}
+
+	if strings.Join(captured.lines, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected the log lines\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(captured.lines, "\n"))
+	}
+}
+
+func TestClauseCallerMustBeParty(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	_, err := f.contract.ClauseRightRequestDelivery(f.as(outsiderId), assetId, validArgs())
+
+	if err == nil || err.Error() != "only the process or the application can execute this operation" {
+		t.Fatalf("expected a third party to be rejected, got %v", err)
+	}
+
+	if requests, _ := f.contract.GetRequestsByAsset(f.as(processId), assetId); len(requests) != 0 {
+		t.Fatalf("expected the rejected call to record nothing, got %d requests", len(requests))
+	}
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.as(applicationId), assetId, validArgs()); err != nil {
+		t.Fatalf("expected a party to succeed, got %s", err)
+	}
+}
+
+func TestGetClauseConfig(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.Parties.Process.MaxProductValue = 50000
+	request.RightRequestDelivery = RightRequestDeliveryConfig{
+		MaxOperations:        5,
+		TimeUnit:             "HOUR",
+		MinIntervalSeconds:   60,
+		WeightTolerance:      2,
+		MaxNumberOfAddresses: 4,
+		Currency:             "BRL",
+		WeightCeiling:        1_000_000,
+		AllowedMSPs:          []string{defaultMSP},
+	}
+
+	assetId := f.init(request)
+
+	configJSON, err := f.contract.GetClauseConfig(f.as(applicationId), assetId, "RightRequestDelivery")
+
+	if err != nil {
+		t.Fatalf("GetClauseConfig: %s", err)
+	}
+
+	var limits RightRequestDeliveryLimits
+
+	if err := json.Unmarshal([]byte(configJSON), &limits); err != nil {
+		t.Fatalf("expected JSON, got %s", err)
+	}
+
+	expected := RightRequestDeliveryLimits{
+		MaxOperations:              5,
+		TimeUnit:                   "HOUR",
+		MinIntervalSeconds:         60,
+		MaxNumberOfAddresses:       4,
+		RequiredWeight:             100,
+		WeightTolerance:            2,
+		ApplicationMaxProductValue: defaultMaxProductValue,
+		ProcessMaxProductValue:     50000,
+		Currency:                   "BRL",
+		NumberOfAddressesCeiling:   defaultSanityCeiling,
+		WeightCeiling:              1_000_000,
+		ProductValueCeiling:        defaultSanityCeiling,
+		AllowedMSPs:                []string{defaultMSP},
+	}
+
+	if !reflect.DeepEqual(limits, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, limits)
+	}
+
+	if _, err := f.contract.GetClauseConfig(f.as(applicationId), assetId, "RightRequestPickup"); err == nil {
+		t.Fatalf("expected an unknown clause to be rejected")
+	}
+}
+
+func TestAddDuration(t *testing.T) {
+	f := newFixture(t)
+
+	start := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	for value, expected := range map[string]time.Time{
+		"P30D":      time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		"PT2H":      time.Date(2024, 1, 31, 2, 0, 0, 0, time.UTC),
+		"P1DT2H30M": time.Date(2024, 2, 1, 2, 30, 0, 0, time.UTC),
+		"P1W":       time.Date(2024, 2, 7, 0, 0, 0, 0, time.UTC),
+		"P1M":       time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC),
+	} {
+		end, err := f.contract.addDuration(start, value)
+
+		if err != nil || !end.Equal(expected) {
+			t.Fatalf("expected %s to end at %s, got %s, %v", value, expected, end, err)
+		}
+	}
+
+	for _, value := range []string{"", "P", "PT", "30D", "P1H", "P-1D"} {
+		if _, err := f.contract.addDuration(start, value); err == nil {
+			t.Fatalf("expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestClauseWindowFromDuration(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.RightRequestDelivery.Window = "P30D"
+
+	assetId := f.signed(request)
+
+	window := f.asset(assetId).RightRequestDelivery.Window
+
+	if !window.Start.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || !window.End.Equal(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the window to be resolved from the begin date, got %s to %s", window.Start, window.End)
+	}
+
+	_, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	if err == nil || !strings.HasPrefix(err.Error(), "outside the clause window") {
+		t.Fatalf("expected a call after the window to be rejected, got %v", err)
+	}
+
+	request.RightRequestDelivery.Window = "P1Y"
+
+	if _, err := f.contract.Init(f.as(applicationId), request); err == nil {
+		t.Fatalf("expected a window past the due date to be rejected")
+	}
+}
+
+func TestSanityBoundsRejectAbsurdArgs(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.RightRequestDelivery.WeightCeiling = 1_000_000
+	request.RightRequestDelivery.ProductValueCeiling = 1_000_000_000
+
+	assetId := f.signed(request)
+
+	absurd := map[string]RightRequestDeliveryArgs{
+		"weight 1000001 exceeds the sanity bound of 1000000":              {NumberOfAddresses: 1, Weight: 1_000_001, ProductValue: 100},
+		"product value 1000000001 exceeds the sanity bound of 1000000000": {NumberOfAddresses: 1, Weight: 100, ProductValue: 1_000_000_001},
+	}
+
+	for message, args := range absurd {
+		_, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, args)
+
+		if err == nil || err.Error() != message {
+			t.Fatalf("expected %q, got %v", message, err)
+		}
+	}
+
+	bounds := RightRequestDeliveryArgs{NumberOfAddresses: 1, Weight: 1_000_000, ProductValue: 1_000_000_000}
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, bounds); err != nil {
+		t.Fatalf("expected values at the bounds to reach business validation, got %s", err)
+	}
+
+	requests, _ := f.contract.GetAllRequests(f.as(processId), assetId)
+
+	if len(requests) != 1 {
+		t.Fatalf("expected only the call within the bounds to be recorded, got %d", len(requests))
+	}
+}
+
+func TestSanityBoundsDefaultWhenNotConfigured(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	args := RightRequestDeliveryArgs{NumberOfAddresses: 1, Weight: 100, ProductValue: defaultSanityCeiling + 1}
+
+	_, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, args)
+
+	if err == nil || !strings.Contains(err.Error(), "exceeds the sanity bound") {
+		t.Fatalf("expected the default ceiling to apply, got %v", err)
+	}
+
+	request := assetRequest()
+	request.RightRequestDelivery.WeightCeiling = -1
+
+	if _, err := f.contract.Init(f.as(applicationId), request); err == nil || err.Error() != "weight ceiling must not be negative" {
+		t.Fatalf("expected a negative ceiling to be rejected, got %v", err)
+	}
+}
+
+func TestExecuteClauseRejectsUnknownArgs(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	for field, argsJSON := range map[string]string{
+		"weigth": `{"numberOfAddresses":1,"weigth":100,"productValue":100}`,
+		"extra":  `{"numberOfAddresses":1,"weight":100,"productValue":100,"extra":true}`,
+	} {
+		_, err := f.contract.ExecuteClause(f.as(processId), assetId, "RightRequestDelivery", argsJSON)
+
+		if err == nil || err.Error() != `invalid arguments for clause RightRequestDelivery: json: unknown field "`+field+`"` {
+			t.Fatalf("expected the unknown field %q to be rejected, got %v", field, err)
+		}
+	}
+
+	requests, _ := f.contract.GetAllRequests(f.as(processId), assetId)
+
+	if len(requests) != 0 {
+		t.Fatalf("expected no request to be recorded, got %d", len(requests))
+	}
+}
+
+func TestDependentClauseNeedsPrerequisite(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.DependsOn = map[string][]string{"RightRequestDelivery": {"RightRequestDelivery"}}
+
+	if _, err := f.contract.Init(f.as(applicationId), request); err == nil || err.Error() != "clause RightRequestDelivery cannot depend on itself" {
+		t.Fatalf("expected a self dependency to be rejected, got %v", err)
+	}
+
+	request.DependsOn = map[string][]string{"RightRequestDelivery": {"LatePenalty"}}
+
+	if _, err := f.contract.Init(f.as(applicationId), request); err == nil || err.Error() != "unknown clause: LatePenalty" {
+		t.Fatalf("expected an unknown prerequisite to be rejected, got %v", err)
+	}
+
+	assetId := f.signed(assetRequest())
+
+	// this contract has a single clause, so the dependency on a second one is written directly
+	asset := f.asset(assetId)
+	asset.DependsOn = map[string][]string{"RightRequestDelivery": {"LatePenalty"}}
+
+	f.contract.putState(f.as(applicationId), assetId, asset)
+
+	_, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	if err == nil || err.Error() != "prerequisite clause not satisfied: LatePenalty" {
+		t.Fatalf("expected the unmet dependency to be rejected, got %v", err)
+	}
+
+	asset = f.asset(assetId)
+	asset.FiredClauses = map[string]time.Time{"LatePenalty": f.now}
+
+	f.contract.putState(f.as(applicationId), assetId, asset)
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs()); err != nil {
+		t.Fatalf("expected the met dependency to pass, got %s", err)
+	}
+
+	if _, fired := f.asset(assetId).FiredClauses["RightRequestDelivery"]; !fired {
+		t.Fatalf("expected the clause to be marked as fired")
+	}
+}
+
+func TestClauseConfigIsFrozenAfterSigning(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.init(assetRequest())
+
+	if err := f.contract.UpdateClauseConfig(f.as(processId), assetId, "RightRequestDelivery", `{"maxNumberOfAddresses":3}`); err != nil {
+		t.Fatalf("expected the config to change before signing, got %s", err)
+	}
+
+	if max := f.asset(assetId).RightRequestDelivery.MaxNumberOfAddresses; max != 3 {
+		t.Fatalf("expected the updated config, got %d", max)
+	}
+
+	for _, id := range []string{applicationId, processId} {
+		f.contract.Sign(f.as(id), assetId, "")
+	}
+
+	err := f.contract.UpdateClauseConfig(f.as(processId), assetId, "RightRequestDelivery", `{"maxNumberOfAddresses":5}`)
+
+	if err == nil || err.Error() != "cannot modify clause config after signing" {
+		t.Fatalf("expected the config to be frozen after signing, got %v", err)
+	}
+
+	if max := f.asset(assetId).RightRequestDelivery.MaxNumberOfAddresses; max != 3 {
+		t.Fatalf("expected the agreed config unchanged, got %d", max)
+	}
+}
+
+func TestClauseRestrictedToAllowedMSPs(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.RightRequestDelivery.AllowedMSPs = []string{"CarrierMSP", "LogisticsMSP"}
+
+	assetId := f.signed(request)
+
+	for _, mspId := range []string{"CarrierMSP", "LogisticsMSP"} {
+		if _, err := f.contract.ClauseRightRequestDelivery(f.asMSP(processId, mspId), assetId, validArgs()); err != nil {
+			t.Fatalf("expected a caller from %s to succeed, got %s", mspId, err)
+		}
+	}
+
+	_, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	if err == nil || err.Error() != "organization not allowed to execute this clause, expected one of CarrierMSP/LogisticsMSP" {
+		t.Fatalf("expected an out-of-org caller to be rejected, got %v", err)
+	}
+
+	openId := f.signed(assetRequest())
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.asMSP(processId, "AnyMSP"), openId, validArgs()); err != nil {
+		t.Fatalf("expected any org when the clause sets none, got %s", err)
+	}
+}
+
+func TestInitRejectsInconsistentClauseConfig(t *testing.T) {
+	f := newFixture(t)
+
+	inconsistent := map[string]RightRequestDeliveryConfig{
+		"max operations must not be negative": {MaxOperations: -1},
+		"RightRequestDelivery: min interval of 120 seconds exceeds the operation window of one MINUTE": {MinIntervalSeconds: 120},
+		"RightRequestDelivery: clause window must end by the due date":                                 {Window: "P1Y"},
+	}
+
+	for message, config := range inconsistent {
+		request := assetRequest()
+		request.RightRequestDelivery = config
+
+		if _, err := f.contract.Init(f.as(applicationId), request); err == nil || err.Error() != message {
+			t.Fatalf("expected %q, got %v", message, err)
+		}
+	}
+
+	request := assetRequest()
+	request.RightRequestDelivery = RightRequestDeliveryConfig{MinIntervalSeconds: 60, Window: "P11M"}
+
+	if _, err := f.contract.Init(f.as(applicationId), request); err != nil {
+		t.Fatalf("expected a consistent config to be accepted, got %s", err)
+	}
+}
+
+func TestWeightedRuleScore(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.RightRequestDelivery.RuleWeights = map[string]int{"numberOfAddresses": 1, "weight": 2, "productValue": 1}
+	request.RightRequestDelivery.ScoreThreshold = 0.75
+	request.RightRequestDelivery.MaxOperations = 10
+
+	assetId := f.signed(request)
+
+	cases := []struct {
+		name  string
+		args  RightRequestDeliveryArgs
+		score float64
+		valid bool
+	}{
+		{"above", validArgs(), 1, true},
+		{"at", RightRequestDeliveryArgs{NumberOfAddresses: 2, Weight: 100, ProductValue: 100}, 0.75, true},
+		{"below", RightRequestDeliveryArgs{NumberOfAddresses: 1, Weight: 50, ProductValue: 100}, 0.5, false},
+	}
+
+	for _, c := range cases {
+		result, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, c.args)
+
+		if err != nil || result.Score != c.score || result.Valid != c.valid {
+			t.Fatalf("expected a score of %v (valid=%t) %s the threshold, got %+v, %v", c.score, c.valid, c.name, result, err)
+		}
+	}
+
+	invalid := map[string]RightRequestDeliveryConfig{
+		"unknown rule: distance":                     {RuleWeights: map[string]int{"distance": 1}},
+		"weight of rule weight must not be negative": {RuleWeights: map[string]int{"weight": -1}},
+		"rule weights must not all be zero":          {RuleWeights: map[string]int{"weight": 0}},
+		"score threshold must be between 0 and 1":    {ScoreThreshold: 1.5},
+	}
+
+	for message, config := range invalid {
+		request := assetRequest()
+		request.RightRequestDelivery = config
+
+		if _, err := f.contract.Init(f.as(applicationId), request); err == nil || err.Error() != message {
+			t.Fatalf("expected %q, got %v", message, err)
+		}
+	}
+}
+
+func TestBusinessHoursInTimezone(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.RightRequestDelivery.MaxOperations = 10
+	request.RightRequestDelivery.BusinessHours = &BusinessHoursConfig{Start: "08:00", End: "18:00", Timezone: "America/Sao_Paulo"}
+
+	localId := f.signed(request)
+
+	request.RightRequestDelivery.BusinessHours = &BusinessHoursConfig{Start: "08:00", End: "18:00"}
+
+	utcId := f.signed(request)
+
+	call := func(assetId string, at string) error {
+		f.now, _ = time.Parse(time.RFC3339, at)
+
+		_, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+		return err
+	}
+
+	// 17:00 in Sao Paulo is 20:00 UTC, and 07:00 there is 10:00 UTC
+	if err := call(localId, "2024-06-01T20:00:00Z"); err != nil {
+		t.Fatalf("expected 17:00 local time to be within business hours, got %s", err)
+	}
+
+	if err := call(localId, "2024-06-02T10:00:00Z"); err == nil || err.Error() != "outside business hours: 08:00 to 18:00" {
+		t.Fatalf("expected 07:00 local time to be rejected, got %v", err)
+	}
+
+	if err := call(utcId, "2024-06-03T20:00:00Z"); err == nil {
+		t.Fatalf("expected 20:00 to be rejected when the interval is in UTC")
+	}
+
+	if err := call(utcId, "2024-06-04T10:00:00Z"); err != nil {
+		t.Fatalf("expected 10:00 to be within UTC business hours, got %s", err)
+	}
+
+	request.RightRequestDelivery.BusinessHours = &BusinessHoursConfig{Start: "08:00", End: "18:00", Timezone: "Mars/Olympus"}
+
+	if _, err := f.contract.Init(f.as(applicationId), request); err == nil || err.Error() != "unknown timezone: Mars/Olympus" {
+		t.Fatalf("expected an unknown timezone to be rejected, got %v", err)
+	}
+}
+
+func TestRequestCancellationWindow(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.RightRequestDelivery.CancellationWindowSeconds = 600
+	request.RightRequestDelivery.MaxOperations = 10
+
+	assetId := f.signed(request)
+
+	first, _ := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+	second, _ := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	f.advance(600 * time.Second)
+
+	if _, err := f.contract.ClauseRequestCancellation(f.as(applicationId), assetId, RequestCancellationArgs{RequestId: first.RequestId}); err == nil || err.Error() != "only the requesting party can cancel request "+first.RequestId {
+		t.Fatalf("expected the other party to be rejected, got %v", err)
+	}
+
+	if _, err := f.contract.ClauseRequestCancellation(f.as(processId), assetId, RequestCancellationArgs{RequestId: first.RequestId}); err != nil {
+		t.Fatalf("expected a cancellation at the end of the window, got %s", err)
+	}
+
+	cancelled, _ := f.contract.getRequest(f.as(processId), assetId, first.RequestId)
+
+	if !cancelled.Cancelled || !cancelled.CancelledAt.Equal(f.now) {
+		t.Fatalf("expected the request to be stored as cancelled, got %+v", cancelled)
+	}
+
+	if usage, _ := f.contract.readClauseUsage(f.as(processId), assetId, "RightRequestDelivery"); usage.TotalProductValue != 100 {
+		t.Fatalf("expected the cancelled value released from the running total, got %d", usage.TotalProductValue)
+	}
+
+	if _, err := f.contract.ClauseRequestCancellation(f.as(processId), assetId, RequestCancellationArgs{RequestId: first.RequestId}); err == nil || err.Error() != "request "+first.RequestId+" is already cancelled" {
+		t.Fatalf("expected a second cancellation to be rejected, got %v", err)
+	}
+
+	f.advance(time.Second)
+
+	_, err := f.contract.ClauseRequestCancellation(f.as(processId), assetId, RequestCancellationArgs{RequestId: second.RequestId})
+
+	if err == nil || err.Error() != "cancellation window for request "+second.RequestId+" closed at 2024-06-01T12:10:00Z" {
+		t.Fatalf("expected a cancellation after the window to be rejected, got %v", err)
+	}
+}
+
+func TestTotalProductValueCap(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.RightRequestDelivery.TotalProductValueCap = 250
+	request.RightRequestDelivery.MaxOperations = 10
+
+	assetId := f.signed(request)
+
+	call := func(args RightRequestDeliveryArgs) (*ValidationResult, error) {
+		return f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, args)
+	}
+
+	if result, err := call(RightRequestDeliveryArgs{NumberOfAddresses: 2, Weight: 100, ProductValue: 100}); err != nil || result.Valid {
+		t.Fatalf("expected a failed request, got %+v, %v", result, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := call(validArgs()); err != nil {
+			t.Fatalf("expected a request under the cap, got %s", err)
+		}
+	}
+
+	_, err := call(validArgs())
+
+	if err == nil || err.Error() != "total product value cap exceeded: 200 of 250 already requested" {
+		t.Fatalf("expected a request crossing the cap to be rejected, got %v", err)
+	}
+
+	if _, err := call(RightRequestDeliveryArgs{NumberOfAddresses: 1, Weight: 100, ProductValue: 50}); err != nil {
+		t.Fatalf("expected a request reaching the cap exactly, got %s", err)
+	}
+
+	usage, _ := f.contract.readClauseUsage(f.as(processId), assetId, "RightRequestDelivery")
+
+	if usage.TotalProductValue != 250 {
+		t.Fatalf("expected only valid requests in the running total, got %d", usage.TotalProductValue)
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	applicationId = "application-id"
+	processId     = "process-id"
+	outsiderId    = "outsider-id"
+
+	defaultMSP = "Org1MSP"
+)
+
+type mockIdentity struct {
+	id    string
+	mspId string
+}
+
+func (m *mockIdentity) GetID() (string, error) {
+	return m.id, nil
+}
+
+func (m *mockIdentity) GetMSPID() (string, error) {
+	return m.mspId, nil
+}
+
+func (m *mockIdentity) GetAttributeValue(attrName string) (string, bool, error) {
+	return "", false, nil
+}
+
+func (m *mockIdentity) AssertAttributeValue(attrName string, attrValue string) error {
+	return fmt.Errorf("attribute %s not found", attrName)
+}
+
+func (m *mockIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return nil, nil
+}
+
+// testStub wraps the shimtest MockStub so single calls can be made to fail.
+type testStub struct {
+	*shimtest.MockStub
+
+	getStateErr error
+	putStateErr error
+
+	reads map[string]int
+
+	history map[string][]*queryresult.KeyModification
+}
+
+func (s *testStub) GetState(key string) ([]byte, error) {
+	if s.getStateErr != nil {
+		return nil, s.getStateErr
+	}
+
+	if s.reads == nil {
+		s.reads = make(map[string]int)
+	}
+
+	s.reads[key]++
+
+	return s.MockStub.GetState(key)
+}
+
+func (s *testStub) PutState(key string, value []byte) error {
+	if s.putStateErr != nil {
+		return s.putStateErr
+	}
+
+	if err := s.MockStub.PutState(key, value); err != nil {
+		return err
+	}
+
+	s.record(key, value, false)
+
+	return nil
+}
+
+func (s *testStub) DelState(key string) error {
+	if err := s.MockStub.DelState(key); err != nil {
+		return err
+	}
+
+	s.record(key, nil, true)
+
+	return nil
+}
+
+func (s *testStub) record(key string, value []byte, isDelete bool) {
+	if s.history == nil {
+		s.history = make(map[string][]*queryresult.KeyModification)
+	}
+
+	s.history[key] = append(s.history[key], &queryresult.KeyModification{
+		TxId:      s.GetTxID(),
+		Value:     value,
+		Timestamp: timestamppb.New(nowFunc()),
+		IsDelete:  isDelete,
+	})
+}
+
+// GetHistoryForKey replays the writes recorded by PutState and DelState, which the shimtest
+// MockStub does not keep.
+func (s *testStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return &historyIterator{modifications: s.history[key]}, nil
+}
+
+// GetQueryResult evaluates the equality and $or selectors the contract builds against the
+// simple keys of the mock state, standing in for CouchDB.
+func (s *testStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	var parsed struct {
+		Selector map[string]interface{} `json:"selector"`
+	}
+
+	if err := json.Unmarshal([]byte(query), &parsed); err != nil {
+		return nil, err
+	}
+
+	results := []*queryresult.KV{}
+
+	for _, key := range s.simpleKeys() {
+		var document map[string]interface{}
+
+		if err := json.Unmarshal(s.State[key], &document); err != nil {
+			continue
+		}
+
+		if matches(document, parsed.Selector) {
+			results = append(results, &queryresult.KV{Key: key, Value: s.State[key]})
+		}
+	}
+
+	return &kvIterator{results: results}, nil
+}
+
+// GetStateByRangeWithPagination pages through the simple keys of the mock state, which the
+// shimtest MockStub leaves unimplemented. The bookmark is the first key of the next page.
+func (s *testStub) GetStateByRangeWithPagination(startKey string, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	results := []*queryresult.KV{}
+	next := ""
+
+	for _, key := range s.simpleKeys() {
+		if key < bookmark || (startKey != "" && key < startKey) || (endKey != "" && key >= endKey) {
+			continue
+		}
+
+		if int32(len(results)) == pageSize {
+			next = key
+			break
+		}
+
+		results = append(results, &queryresult.KV{Key: key, Value: s.State[key]})
+	}
+
+	return &kvIterator{results: results}, &pb.QueryResponseMetadata{FetchedRecordsCount: int32(len(results)), Bookmark: next}, nil
+}
+
+func (s *testStub) simpleKeys() []string {
+	keys := []string{}
+
+	for element := s.Keys.Front(); element != nil; element = element.Next() {
+		if key := element.Value.(string); !strings.HasPrefix(key, "\x00") {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+func matches(document map[string]interface{}, selector map[string]interface{}) bool {
+	for field, expected := range selector {
+		if field == "$or" {
+			matched := false
+
+			for _, alternative := range expected.([]interface{}) {
+				matched = matched || matches(document, alternative.(map[string]interface{}))
+			}
+
+			if !matched {
+				return false
+			}
+
+			continue
+		}
+
+		var value interface{} = document
+
+		for _, part := range strings.Split(field, ".") {
+			object, ok := value.(map[string]interface{})
+
+			if !ok {
+				value = nil
+				break
+			}
+
+			value = object[part]
+		}
+
+		if value != expected {
+			return false
+		}
+	}
+
+	return true
+}
+
+type kvIterator struct {
+	results []*queryresult.KV
+}
+
+func (i *kvIterator) HasNext() bool {
+	return len(i.results) > 0
+}
+
+func (i *kvIterator) Next() (*queryresult.KV, error) {
+	if len(i.results) == 0 {
+		return nil, fmt.Errorf("iterator exhausted")
+	}
+
+	result := i.results[0]
+	i.results = i.results[1:]
+
+	return result, nil
+}
+
+func (i *kvIterator) Close() error {
+	return nil
+}
+
+type historyIterator struct {
+	modifications []*queryresult.KeyModification
+}
+
+func (i *historyIterator) HasNext() bool {
+	return len(i.modifications) > 0
+}
+
+func (i *historyIterator) Next() (*queryresult.KeyModification, error) {
+	if len(i.modifications) == 0 {
+		return nil, fmt.Errorf("iterator exhausted")
+	}
+
+	modification := i.modifications[0]
+	i.modifications = i.modifications[1:]
+
+	return modification, nil
+}
+
+func (i *historyIterator) Close() error {
+	return nil
+}
+
+type fixture struct {
+	t        *testing.T
+	contract *SmartContract
+	stub     *testStub
+	now      time.Time
+	txs      int
+}
+
+func newFixture(t *testing.T) *fixture {
+	f := &fixture{
+		t:        t,
+		contract: new(SmartContract),
+		stub:     &testStub{MockStub: shimtest.NewMockStub("delivery-hiring-r", nil)},
+		now:      time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	previous := nowFunc
+	nowFunc = func() time.Time { return f.now }
+
+	t.Cleanup(func() { nowFunc = previous })
+
+	return f
+}
+
+// as starts a new transaction submitted by id from the default organization.
+func (f *fixture) as(id string) *TransactionContext {
+	return f.asMSP(id, defaultMSP)
+}
+
+func (f *fixture) asMSP(id string, mspId string) *TransactionContext {
+	f.txs++
+	f.stub.MockTransactionStart(fmt.Sprintf("tx%d", f.txs))
+
+	ctx := new(TransactionContext)
+	ctx.SetStub(f.stub)
+	ctx.SetClientIdentity(&mockIdentity{id: id, mspId: mspId})
+
+	return ctx
+}
+
+func (f *fixture) advance(d time.Duration) {
+	f.now = f.now.Add(d)
+}
+
+func assetRequest() AssetRequest {
+	return AssetRequest{
+		BeginDate: "2024-01-01T00:00:00Z",
+		DueDate:   "2024-12-31T00:00:00Z",
+		Parties: PartiesRequest{
+			Application: PartyRequest{Name: "Delivery App", Id: applicationId},
+			Process:     PartyRequest{Name: "Integration Process", Id: processId},
+		},
+	}
+}
+
+func (f *fixture) init(request AssetRequest) string {
+	f.t.Helper()
+
+	assetId, err := f.contract.Init(f.as(applicationId), request)
+
+	if err != nil {
+		f.t.Fatalf("Init: %s", err)
+	}
+
+	return assetId
+}
+
+// signed creates an asset from request and signs it by both parties.
+func (f *fixture) signed(request AssetRequest) string {
+	f.t.Helper()
+
+	assetId := f.init(request)
+
+	for _, id := range []string{applicationId, processId} {
+		if err := f.contract.Sign(f.as(id), assetId, ""); err != nil {
+			f.t.Fatalf("Sign as %s: %s", id, err)
+		}
+	}
+
+	return assetId
+}
+
+func (f *fixture) asset(assetId string) *Asset {
+	f.t.Helper()
+
+	asset, err := f.contract.QueryAsset(f.as(applicationId), assetId)
+
+	if err != nil {
+		f.t.Fatalf("QueryAsset: %s", err)
+	}
+
+	return asset
+}
+
+func validArgs() RightRequestDeliveryArgs {
+	return RightRequestDeliveryArgs{NumberOfAddresses: 1, Weight: 100, ProductValue: 100}
+}
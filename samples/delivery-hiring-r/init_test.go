@@ -0,0 +1,246 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestStoredTimesAreUTC(t *testing.T) {
+	f := newFixture(t)
+
+	f.now = f.now.In(time.FixedZone("BRT", -3*60*60))
+
+	request := assetRequest()
+	request.BeginDate = "2024-01-01T00:00:00-03:00"
+
+	assetId := f.signed(request)
+
+	asset := f.asset(assetId)
+
+	if asset.Parties.Application.SignatureDate.Location() != time.UTC || asset.Parties.Process.SignatureDate.Location() != time.UTC {
+		t.Fatalf("expected the signature dates in UTC, got %s", asset.Parties.Application.SignatureDate)
+	}
+
+	if asset.CreatedAt.Location() != time.UTC {
+		t.Fatalf("expected the creation date in UTC, got %s", asset.CreatedAt)
+	}
+
+	if asset.BeginDate.Location() != time.UTC || !asset.BeginDate.Equal(time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the begin date converted to UTC, got %s", asset.BeginDate)
+	}
+}
+
+func TestStrictBeginDate(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.BeginDate = "2022-01-01T00:00:00Z"
+
+	if _, err := f.contract.Init(f.as(applicationId), request); err != nil {
+		t.Fatalf("expected a back-dated contract when strict mode is off, got %s", err)
+	}
+
+	strictBeginDate = true
+	t.Cleanup(func() { strictBeginDate = false })
+
+	_, err := f.contract.Init(f.as(applicationId), request)
+
+	if err == nil || err.Error() != "begin date is in the past" {
+		t.Fatalf("expected a past begin date to be rejected, got %v", err)
+	}
+
+	request.BeginDate = f.now.Add(-time.Hour).Format(time.RFC3339)
+
+	if _, err := f.contract.Init(f.as(applicationId), request); err != nil {
+		t.Fatalf("expected a begin date within the tolerance, got %s", err)
+	}
+}
+
+func TestInitRejectsEqualBeginAndDueDate(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.DueDate = request.BeginDate
+
+	_, err := f.contract.Init(f.as(applicationId), request)
+
+	if err == nil || err.Error() != "due date must be strictly after begin date" {
+		t.Fatalf("expected identical dates to be rejected, got %v", err)
+	}
+}
+
+func TestInitRecordsCreator(t *testing.T) {
+	f := newFixture(t)
+
+	_, err := f.contract.Init(f.as(outsiderId), assetRequest())
+
+	if err == nil || err.Error() != "only the process or the application can execute this operation" {
+		t.Fatalf("expected a non-party creator to be rejected, got %v", err)
+	}
+
+	assetId, err := f.contract.Init(f.as(processId), assetRequest())
+
+	if err != nil {
+		t.Fatalf("Init: %s", err)
+	}
+
+	if createdBy := f.asset(assetId).CreatedBy; createdBy != processId {
+		t.Fatalf("expected the creator %s, got %q", processId, createdBy)
+	}
+}
+
+func TestInitWithSuppliedId(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.Id = "order-42"
+
+	assetId := f.init(request)
+
+	if assetId != "order-42" || f.asset("order-42").Id != "order-42" {
+		t.Fatalf("expected the supplied id, got %s", assetId)
+	}
+
+	_, err := f.contract.Init(f.as(applicationId), request)
+
+	if err == nil || err.Error() != "asset order-42 already exists" {
+		t.Fatalf("expected a duplicate id to be rejected, got %v", err)
+	}
+
+	generated := f.init(assetRequest())
+
+	if _, err := uuid.Parse(generated); err != nil {
+		t.Fatalf("expected a generated UUID, got %q", generated)
+	}
+}
+
+func TestInitRejectsIdenticalParties(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.Parties.Process.Id = applicationId
+
+	_, err := f.contract.Init(f.as(applicationId), request)
+
+	if err == nil || err.Error() != "application and process must be different parties" {
+		t.Fatalf("expected identical party ids to be rejected, got %v", err)
+	}
+}
+
+func TestInitBatchCommitsValidRequests(t *testing.T) {
+	f := newFixture(t)
+
+	missingDueDate := assetRequest()
+	missingDueDate.DueDate = ""
+
+	foreign := assetRequest()
+	foreign.Parties.Application.Id = outsiderId
+
+	supplied := assetRequest()
+	supplied.Id = "supplied"
+
+	results, err := f.contract.InitBatch(f.as(applicationId), []AssetRequest{assetRequest(), missingDueDate, foreign, supplied, supplied})
+
+	if err != nil {
+		t.Fatalf("InitBatch: %s", err)
+	}
+
+	if len(results) != 5 {
+		t.Fatalf("expected a result per request, got %d", len(results))
+	}
+
+	expected := []string{
+		"",
+		"due date is required",
+		"only the process or the application can execute this operation",
+		"",
+		"asset supplied already exists",
+	}
+
+	for i, result := range results {
+		if result.Error != expected[i] {
+			t.Fatalf("expected result %d to have error %q, got %+v", i, expected[i], result)
+		}
+
+		if (result.Id != "") != (expected[i] == "") {
+			t.Fatalf("expected result %d to carry an id only on success, got %+v", i, result)
+		}
+	}
+
+	if results[3].Id != "supplied" {
+		t.Fatalf("expected the supplied id, got %q", results[3].Id)
+	}
+
+	for _, i := range []int{0, 3} {
+		if exists, _ := f.contract.AssetExists(f.as(applicationId), results[i].Id); !exists {
+			t.Fatalf("expected asset %s to be committed", results[i].Id)
+		}
+	}
+}
+
+func TestInitRequiresDates(t *testing.T) {
+	f := newFixture(t)
+
+	missingBegin := assetRequest()
+	missingBegin.BeginDate = ""
+
+	missingBoth := assetRequest()
+	missingBoth.BeginDate = ""
+	missingBoth.DueDate = ""
+
+	missingDue := assetRequest()
+	missingDue.DueDate = ""
+
+	for message, request := range map[string]AssetRequest{
+		"begin date is required": missingBegin,
+		"due date is required":   missingDue,
+	} {
+		if _, err := f.contract.Init(f.as(applicationId), request); err == nil || err.Error() != message {
+			t.Fatalf("expected %q, got %v", message, err)
+		}
+	}
+
+	if _, err := f.contract.Init(f.as(applicationId), missingBoth); err == nil || err.Error() != "begin date is required" {
+		t.Fatalf("expected the begin date to be reported first, got %v", err)
+	}
+}
+
+func TestDeterministicAssetIdMakesInitIdempotent(t *testing.T) {
+	f := newFixture(t)
+
+	deterministicAssetIds = true
+	t.Cleanup(func() { deterministicAssetIds = false })
+
+	assetId := f.init(assetRequest())
+	assetAsBytes := string(f.stub.State[assetId])
+
+	f.advance(time.Hour)
+
+	retry := assetRequest()
+	retry.BeginDate = "2023-12-31T21:00:00-03:00"
+
+	retriedId, err := f.contract.Init(f.as(processId), retry)
+
+	if err != nil || retriedId != assetId {
+		t.Fatalf("expected the retry to return %s, got %s, %v", assetId, retriedId, err)
+	}
+
+	if string(f.stub.State[assetId]) != assetAsBytes {
+		t.Fatalf("expected the retry to leave the stored asset untouched")
+	}
+
+	other := assetRequest()
+	other.DueDate = "2024-11-30T00:00:00Z"
+
+	if otherId := f.init(other); otherId == assetId {
+		t.Fatalf("expected different dates to produce a different id")
+	}
+
+	deterministicAssetIds = false
+
+	if f.init(assetRequest()) == assetId {
+		t.Fatalf("expected a random id when the mode is off")
+	}
+}
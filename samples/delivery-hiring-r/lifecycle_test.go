@@ -0,0 +1,600 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRenew(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.RightRequestDelivery.Window = "P1M"
+
+	assetId := f.signed(request)
+
+	if _, err := f.contract.Renew(f.as(applicationId), assetId, "2025-01-01T00:00:00Z", "2025-12-31T00:00:00Z"); err == nil {
+		t.Fatalf("expected a contract far from its due date to not be renewable")
+	}
+
+	f.advance(200 * 24 * time.Hour)
+
+	if _, err := f.contract.Renew(f.as(outsiderId), assetId, "2025-01-01T00:00:00Z", "2025-12-31T00:00:00Z"); err == nil {
+		t.Fatalf("expected a non-party to be rejected")
+	}
+
+	renewedId, err := f.contract.Renew(f.as(processId), assetId, "2025-01-01T00:00:00Z", "2025-12-31T00:00:00Z")
+
+	if err != nil {
+		t.Fatalf("Renew: %s", err)
+	}
+
+	renewed := f.asset(renewedId)
+
+	if renewed.PreviousAssetId != assetId {
+		t.Fatalf("expected the renewed asset to reference %s, got %q", assetId, renewed.PreviousAssetId)
+	}
+
+	if renewed.IsSigned || renewed.Parties.Application.IsSigned || renewed.Parties.Process.IsSigned {
+		t.Fatalf("expected the renewed asset to start unsigned")
+	}
+
+	if renewed.Parties.Application.Id != applicationId || renewed.Parties.Process.Id != processId {
+		t.Fatalf("expected the renewed asset to keep the parties, got %+v", renewed.Parties)
+	}
+
+	window := renewed.RightRequestDelivery.Window
+
+	if !window.Start.Equal(renewed.BeginDate) || !window.End.Equal(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the clause window to move with the begin date, got %s to %s", window.Start, window.End)
+	}
+}
+
+func TestRenewRejectsCancelledAndDeletedContracts(t *testing.T) {
+	f := newFixture(t)
+
+	cancelledId := f.init(assetRequest())
+
+	if err := f.contract.Cancel(f.as(applicationId), cancelledId, "no longer needed"); err != nil {
+		t.Fatalf("Cancel: %s", err)
+	}
+
+	deletedId := f.init(assetRequest())
+
+	if err := f.contract.ArchiveAsset(f.as(processId), deletedId); err != nil {
+		t.Fatalf("ArchiveAsset: %s", err)
+	}
+
+	f.advance(200 * 24 * time.Hour)
+
+	if _, err := f.contract.Renew(f.as(applicationId), cancelledId, "2025-01-01T00:00:00Z", "2025-12-31T00:00:00Z"); err == nil || err.Error() != "contract cancelled" {
+		t.Fatalf("expected renewing a cancelled contract to be rejected, got %v", err)
+	}
+
+	if _, err := f.contract.Renew(f.as(applicationId), deletedId, "2025-01-01T00:00:00Z", "2025-12-31T00:00:00Z"); !errors.Is(err, ErrAssetNotFound) {
+		t.Fatalf("expected renewing a deleted contract to be rejected, got %v", err)
+	}
+}
+
+func TestGetContractStatus(t *testing.T) {
+	f := newFixture(t)
+
+	status := func(assetId string) string {
+		t.Helper()
+
+		status, err := f.contract.GetContractStatus(f.as(applicationId), assetId)
+
+		if err != nil {
+			t.Fatalf("GetContractStatus: %s", err)
+		}
+
+		return status
+	}
+
+	assetId := f.init(assetRequest())
+
+	if got := status(assetId); got != ContractDraft {
+		t.Fatalf("expected %s, got %s", ContractDraft, got)
+	}
+
+	f.contract.Sign(f.as(applicationId), assetId, "")
+
+	if got := status(assetId); got != ContractPendingSignatures {
+		t.Fatalf("expected %s, got %s", ContractPendingSignatures, got)
+	}
+
+	f.contract.Sign(f.as(processId), assetId, "")
+
+	if got := status(assetId); got != ContractActive {
+		t.Fatalf("expected %s, got %s", ContractActive, got)
+	}
+
+	f.contract.Suspend(f.as(processId), assetId)
+
+	if got := status(assetId); got != ContractSuspended {
+		t.Fatalf("expected %s, got %s", ContractSuspended, got)
+	}
+
+	f.contract.Resume(f.as(processId), assetId)
+
+	terminatedId := f.signed(assetRequest())
+
+	for _, id := range []string{applicationId, processId} {
+		if _, err := f.contract.Terminate(f.as(id), terminatedId, "breach"); err != nil {
+			t.Fatalf("Terminate: %s", err)
+		}
+	}
+
+	if got := status(terminatedId); got != ContractTerminated {
+		t.Fatalf("expected %s, got %s", ContractTerminated, got)
+	}
+
+	cancelledId := f.init(assetRequest())
+
+	f.contract.Cancel(f.as(applicationId), cancelledId, "no longer needed")
+
+	if got := status(cancelledId); got != ContractCancelled {
+		t.Fatalf("expected %s, got %s", ContractCancelled, got)
+	}
+
+	f.now = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if got := status(assetId); got != ContractExpired {
+		t.Fatalf("expected %s, got %s", ContractExpired, got)
+	}
+}
+
+func TestSuspendBlocksClausesUntilResumed(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	if err := f.contract.Suspend(f.as(outsiderId), assetId); err == nil {
+		t.Fatalf("expected a non-party to be rejected")
+	}
+
+	if err := f.contract.Suspend(f.as(applicationId), assetId); err != nil {
+		t.Fatalf("Suspend: %s", err)
+	}
+
+	_, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	if err == nil || err.Error() != "contract suspended" {
+		t.Fatalf("expected the clause to be blocked while suspended, got %v", err)
+	}
+
+	asset := f.asset(assetId)
+
+	if !asset.IsSigned || !asset.Parties.Application.IsSigned || !asset.Parties.Process.IsSigned {
+		t.Fatalf("expected suspending to leave the signatures intact")
+	}
+
+	if asset.SuspendedAt.IsZero() {
+		t.Fatalf("expected SuspendedAt to be set")
+	}
+
+	if err := f.contract.Resume(f.as(processId), assetId); err != nil {
+		t.Fatalf("Resume: %s", err)
+	}
+
+	if f.asset(assetId).ResumedAt.IsZero() {
+		t.Fatalf("expected ResumedAt to be set")
+	}
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs()); err != nil {
+		t.Fatalf("expected resuming to restore the clause, got %s", err)
+	}
+
+	if err := f.contract.Resume(f.as(processId), assetId); err == nil {
+		t.Fatalf("expected resuming an active contract to be rejected")
+	}
+}
+
+func TestClauseWindowIncludesBoundaries(t *testing.T) {
+	f := newFixture(t)
+
+	beginDate := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	dueDate := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	f.now = beginDate.Add(-time.Second)
+
+	assetId := f.signed(assetRequest())
+
+	_, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	if err == nil || err.Error() != "the current date is before the start date" {
+		t.Fatalf("expected a call before the begin date to be rejected, got %v", err)
+	}
+
+	f.now = beginDate
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs()); err != nil {
+		t.Fatalf("expected a call at exactly the begin date, got %s", err)
+	}
+
+	f.now = dueDate
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs()); err != nil {
+		t.Fatalf("expected a call at exactly the due date, got %s", err)
+	}
+
+	f.now = dueDate.Add(time.Second)
+
+	result, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	if err != nil || result.Valid || !reflect.DeepEqual(result.FailedRules, []string{contractExpiredRule}) {
+		t.Fatalf("expected the call that emits ContractExpired to be rejected as a result, got %+v, %v", result, err)
+	}
+
+	_, err = f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	if err == nil || err.Error() != "asset expired. The current date is after the due date" {
+		t.Fatalf("expected a call after the due date to be rejected, got %v", err)
+	}
+}
+
+func TestGetExpiringContracts(t *testing.T) {
+	f := newFixture(t)
+
+	rangePageSize = 2
+	t.Cleanup(func() { rangePageSize = 100 })
+
+	expiring := func(dueDate string) AssetRequest {
+		request := assetRequest()
+		request.DueDate = dueDate
+
+		return request
+	}
+
+	soon := f.signed(expiring("2024-06-05T00:00:00Z"))
+	edge := f.signed(expiring("2024-06-11T12:00:00Z"))
+
+	f.signed(expiring("2024-07-01T00:00:00Z"))
+	f.init(expiring("2024-06-03T00:00:00Z"))
+
+	expired := f.signed(expiring("2024-06-02T00:00:00Z"))
+
+	f.advance(36 * time.Hour)
+
+	assets, err := f.contract.GetExpiringContracts(f.as(applicationId), 10)
+
+	if err != nil {
+		t.Fatalf("GetExpiringContracts: %s", err)
+	}
+
+	ids := map[string]bool{}
+
+	for _, asset := range assets {
+		ids[asset.Id] = true
+	}
+
+	if len(assets) != 2 || !ids[soon] || !ids[edge] || ids[expired] {
+		t.Fatalf("expected only the active contracts due within 10 days, got %v", ids)
+	}
+
+	if _, err := f.contract.GetExpiringContracts(f.as(applicationId), -1); err == nil {
+		t.Fatalf("expected a negative window to be rejected")
+	}
+}
+
+func TestGracePeriodAfterDueDate(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.GracePeriodSeconds = 3600
+
+	assetId := f.signed(request)
+
+	result, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	if err != nil || result.InGracePeriod {
+		t.Fatalf("expected a call inside the window outside the grace period, got %+v, %v", result, err)
+	}
+
+	f.now = time.Date(2024, 12, 31, 0, 30, 0, 0, time.UTC)
+
+	result, err = f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	if err != nil || !result.InGracePeriod {
+		t.Fatalf("expected a call in the grace period to succeed with the signal set, got %+v, %v", result, err)
+	}
+
+	f.now = time.Date(2024, 12, 31, 1, 0, 1, 0, time.UTC)
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs()); err == nil {
+		t.Fatalf("expected a call past the grace period to be rejected")
+	}
+
+	request.GracePeriodSeconds = -1
+
+	_, err = f.contract.Init(f.as(applicationId), request)
+
+	if err == nil || err.Error() != "grace period must not be negative" {
+		t.Fatalf("expected a negative grace period to be rejected, got %v", err)
+	}
+}
+
+func TestContractExpiredEventFiresOnce(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.GracePeriodSeconds = 3600
+
+	assetId := f.signed(request)
+
+	notified, err := f.contract.NotifyExpiration(f.as(applicationId), assetId)
+
+	if err != nil || notified {
+		t.Fatalf("expected no event before the due date, got %t, %v", notified, err)
+	}
+
+	f.now = time.Date(2024, 12, 31, 0, 30, 0, 0, time.UTC)
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs()); err != nil {
+		t.Fatalf("ClauseRightRequestDelivery: %s", err)
+	}
+
+	notified, err = f.contract.NotifyExpiration(f.as(applicationId), assetId)
+
+	if err != nil || notified {
+		t.Fatalf("expected the event to not fire again, got %t, %v", notified, err)
+	}
+
+	if len(f.stub.ChaincodeEventsChannel) != 1 {
+		t.Fatalf("expected one event, got %d", len(f.stub.ChaincodeEventsChannel))
+	}
+
+	if event := <-f.stub.ChaincodeEventsChannel; event.EventName != contractExpiredEvent {
+		t.Fatalf("expected %s, got %s", contractExpiredEvent, event.EventName)
+	}
+
+	events, err := f.contract.GetEvents(f.as(applicationId), assetId, 0)
+
+	if err != nil || len(events) != 1 || events[0].Name != contractExpiredEvent || events[0].Seq != 1 {
+		t.Fatalf("expected the event to be recorded once, got %+v, %v", events, err)
+	}
+
+	if !f.asset(assetId).ExpiredNotified {
+		t.Fatalf("expected ExpiredNotified to be stored")
+	}
+}
+
+func TestExpiredClauseCallEmitsContractExpired(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	f.now = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	result, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	if err != nil || result.Valid || !reflect.DeepEqual(result.FailedRules, []string{contractExpiredRule}) {
+		t.Fatalf("expected the expired call to be rejected as a result so that it commits, got %+v, %v", result, err)
+	}
+
+	if !f.asset(assetId).ExpiredNotified {
+		t.Fatalf("expected ExpiredNotified to be stored")
+	}
+
+	if event := <-f.stub.ChaincodeEventsChannel; event.EventName != contractExpiredEvent {
+		t.Fatalf("expected %s, got %s", contractExpiredEvent, event.EventName)
+	}
+
+	_, err = f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	if err == nil || err.Error() != "asset expired. The current date is after the due date" {
+		t.Fatalf("expected later calls to be rejected with an error, got %v", err)
+	}
+
+	if len(f.stub.ChaincodeEventsChannel) != 0 {
+		t.Fatalf("expected the event to fire once, got %d more", len(f.stub.ChaincodeEventsChannel))
+	}
+}
+
+func TestCancelUnsignedContract(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.init(assetRequest())
+
+	f.contract.Sign(f.as(processId), assetId, "")
+
+	if err := f.contract.Cancel(f.as(applicationId), assetId, "wrong carrier"); err != nil {
+		t.Fatalf("Cancel: %s", err)
+	}
+
+	asset := f.asset(assetId)
+
+	if !asset.Cancelled || asset.CancelledReason != "wrong carrier" || !asset.CancelledAt.Equal(f.now) {
+		t.Fatalf("expected the cancellation to be recorded, got %t, %q, %s", asset.Cancelled, asset.CancelledReason, asset.CancelledAt)
+	}
+
+	err := f.contract.Sign(f.as(applicationId), assetId, "")
+
+	if err == nil || err.Error() != "contract cancelled" {
+		t.Fatalf("expected signing a cancelled contract to be rejected, got %v", err)
+	}
+
+	signedId := f.signed(assetRequest())
+
+	err = f.contract.Cancel(f.as(applicationId), signedId, "")
+
+	if err == nil || err.Error() != "only unsigned contracts can be cancelled" {
+		t.Fatalf("expected cancelling a signed contract to be rejected, got %v", err)
+	}
+}
+
+func TestCountAssetsByStatus(t *testing.T) {
+	f := newFixture(t)
+
+	rangePageSize = 2
+	t.Cleanup(func() { rangePageSize = 100 })
+
+	f.init(assetRequest())
+	f.init(assetRequest())
+	f.signed(assetRequest())
+
+	pendingId := f.init(assetRequest())
+
+	f.contract.Sign(f.as(processId), pendingId, "")
+
+	cancelledId := f.init(assetRequest())
+
+	f.contract.Cancel(f.as(processId), cancelledId, "")
+
+	archivedId := f.init(assetRequest())
+
+	f.contract.ArchiveAsset(f.as(processId), archivedId)
+
+	expiring := assetRequest()
+	expiring.DueDate = "2024-06-02T00:00:00Z"
+
+	f.signed(expiring)
+	f.advance(24 * time.Hour)
+
+	counts, err := f.contract.CountAssetsByStatus(f.as(applicationId))
+
+	if err != nil {
+		t.Fatalf("CountAssetsByStatus: %s", err)
+	}
+
+	expected := map[string]int{ContractDraft: 2, ContractPendingSignatures: 1, ContractActive: 1, ContractCancelled: 1, ContractExpired: 1}
+
+	if len(counts) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, counts)
+	}
+
+	for status, count := range expected {
+		if counts[status] != count {
+			t.Fatalf("expected %v, got %v", expected, counts)
+		}
+	}
+}
+
+func TestTimeUntilActiveAndExpiry(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.BeginDate = "2024-06-02T12:00:00Z"
+	request.DueDate = "2024-06-05T12:00:00Z"
+
+	futureId := f.init(request)
+
+	if seconds, err := f.contract.TimeUntilActive(f.as(applicationId), futureId); err != nil || seconds != 86400 {
+		t.Fatalf("expected a day until the begin date, got %d, %v", seconds, err)
+	}
+
+	if seconds, err := f.contract.TimeUntilExpiry(f.as(applicationId), futureId); err != nil || seconds != 4*86400 {
+		t.Fatalf("expected four days until the due date, got %d, %v", seconds, err)
+	}
+
+	activeId := f.init(assetRequest())
+
+	if seconds, _ := f.contract.TimeUntilActive(f.as(applicationId), activeId); seconds != 0 {
+		t.Fatalf("expected zero for an active contract, got %d", seconds)
+	}
+
+	f.advance(365 * 24 * time.Hour)
+
+	if seconds, _ := f.contract.TimeUntilExpiry(f.as(applicationId), activeId); seconds != 0 {
+		t.Fatalf("expected zero past the due date, got %d", seconds)
+	}
+
+	if _, err := f.contract.TimeUntilActive(f.as(applicationId), "missing"); err == nil {
+		t.Fatalf("expected a missing asset to be rejected")
+	}
+}
+
+func TestClockOverrideSimulatesExpiry(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	if created := f.asset(assetId).CreatedAt; !created.Equal(f.now) {
+		t.Fatalf("expected the creation date from the overridden clock, got %s", created)
+	}
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs()); err != nil {
+		t.Fatalf("ClauseRightRequestDelivery: %s", err)
+	}
+
+	f.now = time.Date(2024, 12, 31, 0, 0, 1, 0, time.UTC)
+
+	result, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	if err != nil || result.Valid {
+		t.Fatalf("expected the clause to be rejected once the clock passes the due date, got %+v, %v", result, err)
+	}
+}
+
+func TestGetContractDuration(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.DueDate = "2024-01-10T05:30:15Z"
+
+	assetId := f.init(request)
+
+	duration, err := f.contract.GetContractDuration(f.as(applicationId), assetId)
+
+	if err != nil {
+		t.Fatalf("GetContractDuration: %s", err)
+	}
+
+	if duration.Seconds != 797415 {
+		t.Fatalf("expected 797415 seconds, got %d", duration.Seconds)
+	}
+
+	expected := map[string]int{"WEEK": 1, "DAY": 2, "HOUR": 5, "MINUTE": 30, "SECOND": 15}
+
+	if !reflect.DeepEqual(duration.Breakdown, expected) {
+		t.Fatalf("expected the breakdown %v, got %v", expected, duration.Breakdown)
+	}
+
+	if duration.Text != "1 week, 2 day, 5 hour, 30 minute, 15 second" {
+		t.Fatalf("unexpected text %q", duration.Text)
+	}
+}
+
+func TestGetRemainingGrace(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.GracePeriodSeconds = 3600
+
+	assetId := f.signed(request)
+	noGraceId := f.signed(assetRequest())
+
+	remaining := func(assetId string) int64 {
+		t.Helper()
+
+		seconds, err := f.contract.GetRemainingGrace(f.as(applicationId), assetId)
+
+		if err != nil {
+			t.Fatalf("GetRemainingGrace: %s", err)
+		}
+
+		return seconds
+	}
+
+	if seconds := remaining(assetId); seconds != 3600 {
+		t.Fatalf("expected the whole grace period before the due date, got %d", seconds)
+	}
+
+	f.now = time.Date(2024, 12, 31, 0, 30, 0, 0, time.UTC)
+
+	if seconds := remaining(assetId); seconds != 1800 {
+		t.Fatalf("expected 1800 seconds inside the grace period, got %d", seconds)
+	}
+
+	if seconds := remaining(noGraceId); seconds != 0 {
+		t.Fatalf("expected zero without a grace period, got %d", seconds)
+	}
+
+	f.now = time.Date(2024, 12, 31, 1, 0, 1, 0, time.UTC)
+
+	if seconds := remaining(assetId); seconds != 0 {
+		t.Fatalf("expected zero past the grace period, got %d", seconds)
+	}
+}
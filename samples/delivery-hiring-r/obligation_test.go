@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func obligationRequest() AssetRequest {
+	request := assetRequest()
+	request.Obligations = []ObligationRequest{
+		{Party: RoleProcess, Description: "ship the order", Deadline: "2024-06-10T00:00:00Z"},
+		{Party: RoleApplication, Description: "pay the invoice", Deadline: "2024-06-02T00:00:00Z"},
+	}
+
+	return request
+}
+
+func obligationByDescription(asset *Asset, description string) Obligation {
+	for _, obligation := range asset.Obligations {
+		if obligation.Description == description {
+			return obligation
+		}
+	}
+
+	return Obligation{}
+}
+
+func TestFulfillObligationOnTime(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(obligationRequest())
+
+	ship := obligationByDescription(f.asset(assetId), "ship the order")
+
+	if err := f.contract.FulfillObligation(f.as(applicationId), assetId, ship.Id); err == nil {
+		t.Fatalf("expected only the obligated party to fulfill it")
+	}
+
+	if err := f.contract.FulfillObligation(f.as(processId), assetId, ship.Id); err != nil {
+		t.Fatalf("FulfillObligation: %s", err)
+	}
+
+	ship = obligationByDescription(f.asset(assetId), "ship the order")
+
+	if !ship.Fulfilled || ship.Overdue || !ship.FulfilledAt.Equal(f.now) {
+		t.Fatalf("expected the obligation fulfilled on time, got %+v", ship)
+	}
+
+	if err := f.contract.FulfillObligation(f.as(processId), assetId, ship.Id); err == nil {
+		t.Fatalf("expected a fulfilled obligation to be rejected")
+	}
+}
+
+func TestCheckOverdueObligations(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(obligationRequest())
+
+	overdue, err := f.contract.CheckOverdueObligations(f.as(applicationId), assetId)
+
+	if err != nil || len(overdue) != 0 {
+		t.Fatalf("expected nothing overdue yet, got %v, %v", overdue, err)
+	}
+
+	f.advance(2 * 24 * time.Hour)
+
+	overdue, err = f.contract.CheckOverdueObligations(f.as(applicationId), assetId)
+
+	if err != nil || len(overdue) != 1 || overdue[0].Description != "pay the invoice" || !overdue[0].Overdue {
+		t.Fatalf("expected the unpaid invoice to be overdue, got %+v, %v", overdue, err)
+	}
+
+	if !obligationByDescription(f.asset(assetId), "pay the invoice").Overdue {
+		t.Fatalf("expected the overdue mark to be stored")
+	}
+
+	pay := obligationByDescription(f.asset(assetId), "pay the invoice")
+
+	if err := f.contract.FulfillObligation(f.as(applicationId), assetId, pay.Id); err != nil {
+		t.Fatalf("FulfillObligation: %s", err)
+	}
+
+	if pay = obligationByDescription(f.asset(assetId), "pay the invoice"); !pay.Fulfilled || !pay.Overdue {
+		t.Fatalf("expected a late fulfillment to stay marked overdue, got %+v", pay)
+	}
+}
+
+func TestProhibitionBlocksParty(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.Prohibitions = []Prohibition{{Party: RoleApplication, Operation: "RightRequestDelivery"}}
+
+	assetId := f.signed(request)
+
+	_, err := f.contract.ClauseRightRequestDelivery(f.as(applicationId), assetId, validArgs())
+
+	if err == nil || err.Error() != "operation prohibited for this party" {
+		t.Fatalf("expected the prohibited party to be blocked, got %v", err)
+	}
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs()); err != nil {
+		t.Fatalf("expected the other party to proceed, got %s", err)
+	}
+
+	request.Prohibitions = []Prohibition{{Party: "carrier", Operation: "RightRequestDelivery"}}
+
+	if _, err := f.contract.Init(f.as(applicationId), request); err == nil {
+		t.Fatalf("expected a prohibition on an unknown role to be rejected")
+	}
+}
@@ -0,0 +1,487 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryAssetsByParty(t *testing.T) {
+	f := newFixture(t)
+
+	first := f.init(assetRequest())
+	second := f.init(assetRequest())
+
+	other := assetRequest()
+	other.Parties.Process.Id = "other-process-id"
+
+	f.init(other)
+
+	assets, err := f.contract.QueryAssetsByParty(f.as(processId), processId)
+
+	if err != nil {
+		t.Fatalf("QueryAssetsByParty: %s", err)
+	}
+
+	ids := []string{}
+
+	for _, asset := range assets {
+		ids = append(ids, asset.Id)
+
+		if asset.Parties.Process.Id != processId {
+			t.Fatalf("expected only assets of %s, got %s", processId, asset.Parties.Process.Id)
+		}
+	}
+
+	expected := []string{first, second}
+
+	sort.Strings(ids)
+	sort.Strings(expected)
+
+	if len(ids) != 2 || ids[0] != expected[0] || ids[1] != expected[1] {
+		t.Fatalf("expected assets %v, got %v", expected, ids)
+	}
+}
+
+func TestPartyPrivateData(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.init(assetRequest())
+
+	if err := f.contract.PutPartyPrivateData(f.as(applicationId), assetId, RoleApplication); err == nil {
+		t.Fatalf("expected a missing transient contact to be rejected")
+	}
+
+	f.stub.SetTransient(map[string][]byte{"contact": []byte(`{"email":"ops@delivery.app","phone":"+55 55 5555"}`)})
+
+	if err := f.contract.PutPartyPrivateData(f.asMSP(processId, "Org2MSP"), assetId, RoleApplication); err == nil {
+		t.Fatalf("expected the process to be unable to write the application's contact")
+	}
+
+	if err := f.contract.PutPartyPrivateData(f.as(applicationId), assetId, RoleApplication); err != nil {
+		t.Fatalf("PutPartyPrivateData: %s", err)
+	}
+
+	contact, err := f.contract.GetPartyPrivateData(f.as(applicationId), assetId, RoleApplication)
+
+	if err != nil || contact.Email != "ops@delivery.app" || contact.Phone != "+55 55 5555" {
+		t.Fatalf("expected the owning organization to read the contact, got %+v, %v", contact, err)
+	}
+
+	if _, err := f.contract.GetPartyPrivateData(f.asMSP(processId, "Org2MSP"), assetId, RoleApplication); err == nil {
+		t.Fatalf("expected the counterparty organization to not read the contact")
+	}
+}
+
+func TestListClauses(t *testing.T) {
+	f := newFixture(t)
+
+	clauses, err := f.contract.ListClauses(f.as(applicationId))
+
+	if err != nil {
+		t.Fatalf("ListClauses: %s", err)
+	}
+
+	for _, clause := range clauses {
+		if clause.Name != "RightRequestDelivery" {
+			continue
+		}
+
+		arguments := map[string]string{}
+
+		for _, argument := range clause.Arguments {
+			arguments[argument.Name] = argument.Type
+		}
+
+		for _, name := range []string{"numberOfAddresses", "weight", "productValue"} {
+			if arguments[name] != "int" {
+				t.Fatalf("expected the int argument %s, got %+v", name, clause.Arguments)
+			}
+		}
+
+		return
+	}
+
+	t.Fatalf("expected RightRequestDelivery among %+v", clauses)
+}
+
+func TestGetParty(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.init(assetRequest())
+
+	for role, id := range map[string]string{RoleApplication: applicationId, RoleProcess: processId} {
+		party, err := f.contract.GetParty(f.as(applicationId), assetId, role)
+
+		if err != nil || party.Id != id {
+			t.Fatalf("expected the %s to be %s, got %+v, %v", role, id, party, err)
+		}
+	}
+
+	_, err := f.contract.GetParty(f.as(applicationId), assetId, "carrier")
+
+	if err == nil || err.Error() != "unknown role: carrier, expected one of application/process" {
+		t.Fatalf("expected an unknown role to be rejected, got %v", err)
+	}
+}
+
+func TestGetMyAssets(t *testing.T) {
+	t.Cleanup(func() { useRichQueries = false })
+
+	for _, richQueries := range []bool{false, true} {
+		f := newFixture(t)
+
+		useRichQueries = richQueries
+
+		mine := f.init(assetRequest())
+
+		other := assetRequest()
+		other.Parties.Application.Id = "other-application-id"
+
+		theirs, err := f.contract.Init(f.as("other-application-id"), other)
+
+		if err != nil {
+			t.Fatalf("Init: %s", err)
+		}
+
+		for id, expected := range map[string][]string{applicationId: {mine}, "other-application-id": {theirs}, processId: {mine, theirs}} {
+			assets, err := f.contract.GetMyAssets(f.as(id))
+
+			if err != nil {
+				t.Fatalf("GetMyAssets: %s", err)
+			}
+
+			ids := []string{}
+
+			for _, asset := range assets {
+				ids = append(ids, asset.Id)
+			}
+
+			sort.Strings(ids)
+			sort.Strings(expected)
+
+			if strings.Join(ids, ",") != strings.Join(expected, ",") {
+				t.Fatalf("expected %s to see %v with rich queries %t, got %v", id, expected, richQueries, ids)
+			}
+		}
+	}
+}
+
+func TestGetContractSummary(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	for i := 0; i < 2; i++ {
+		f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+	}
+
+	summary, err := f.contract.GetContractSummary(f.as(applicationId), assetId)
+
+	if err != nil {
+		t.Fatalf("GetContractSummary: %s", err)
+	}
+
+	asset := f.asset(assetId)
+
+	if summary.Id != assetId || summary.ApplicationName != asset.Parties.Application.Name || summary.ProcessName != asset.Parties.Process.Name {
+		t.Fatalf("expected the summary to match the asset, got %+v", summary)
+	}
+
+	if summary.Status != ContractActive || summary.SignedCount != 2 || summary.RequestCount != 2 {
+		t.Fatalf("expected an active contract with 2 signatures and 2 requests, got %+v", summary)
+	}
+
+	if remaining := summary.RemainingOperations["RightRequestDelivery"]; remaining.Remaining != 1 || remaining.WindowExpired {
+		t.Fatalf("expected 1 operation left, got %+v", summary.RemainingOperations)
+	}
+
+	if _, limited := summary.RemainingOperations["RequestCancellation"]; limited {
+		t.Fatalf("expected clauses without a limit to be left out")
+	}
+}
+
+func TestGetAuditTrailIsChronological(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.init(assetRequest())
+
+	f.advance(time.Hour)
+	f.contract.Sign(f.as(applicationId), assetId, "")
+
+	f.advance(time.Hour)
+	f.contract.Sign(f.as(processId), assetId, "")
+
+	f.advance(time.Hour)
+	f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	f.advance(time.Hour)
+	f.contract.ExtendDueDate(f.as(applicationId), assetId, "2025-06-30T00:00:00Z")
+
+	trail, err := f.contract.GetAuditTrail(f.as(applicationId), assetId)
+
+	if err != nil {
+		t.Fatalf("GetAuditTrail: %s", err)
+	}
+
+	types := []string{}
+
+	for i, entry := range trail {
+		types = append(types, entry.Type)
+
+		if i > 0 && entry.Timestamp.Before(trail[i-1].Timestamp) {
+			t.Fatalf("expected the trail in chronological order, got %s after %s", entry.Timestamp, trail[i-1].Timestamp)
+		}
+	}
+
+	expected := "state,state,signature,state,signature,state,clause,amendment,state"
+
+	if strings.Join(types, ",") != expected {
+		t.Fatalf("expected the entry types %s, got %s", expected, strings.Join(types, ","))
+	}
+
+	if trail[2].ClientId != applicationId || trail[4].ClientId != processId || trail[6].ClientId != processId {
+		t.Fatalf("expected the entries to carry their client, got %+v", trail)
+	}
+}
+
+func TestGetVersion(t *testing.T) {
+	f := newFixture(t)
+
+	version, err := f.contract.GetVersion(f.as(outsiderId))
+
+	if err != nil || version != `{"contractVersion":"`+contractVersion+`","engineVersion":"`+engineVersion+`"}` {
+		t.Fatalf("expected the embedded versions, got %s, %v", version, err)
+	}
+
+	// the build stamps contractVersion with -ldflags -X
+	previous := contractVersion
+	contractVersion = "1.2.0"
+	t.Cleanup(func() { contractVersion = previous })
+
+	if version, _ := f.contract.GetVersion(f.as(outsiderId)); !strings.Contains(version, `"contractVersion":"1.2.0"`) {
+		t.Fatalf("expected the stamped contract version, got %s", version)
+	}
+}
+
+func TestGetEventsReplaysFromSequence(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+	otherId := f.signed(assetRequest())
+
+	emit := func(assetId string, name string) {
+		t.Helper()
+
+		ctx := f.as(applicationId)
+		asset, _ := f.contract.QueryAsset(ctx, assetId)
+
+		if err := f.contract.emitEvent(ctx, assetId, asset, name, []byte(`{}`)); err != nil {
+			t.Fatalf("emitEvent: %s", err)
+		}
+
+		if err := f.contract.emitEvent(ctx, assetId, asset, name, []byte(`{}`)); err == nil {
+			t.Fatalf("expected a second event in the same transaction to be rejected")
+		}
+
+		f.contract.putState(ctx, assetId, asset)
+	}
+
+	for _, name := range []string{"First", "Second", "Third"} {
+		emit(assetId, name)
+	}
+
+	emit(otherId, "Other")
+
+	if len(f.stub.ChaincodeEventsChannel) != 4 {
+		t.Fatalf("expected every event to be set on the stub, got %d", len(f.stub.ChaincodeEventsChannel))
+	}
+
+	names := func(sinceSeq int) string {
+		t.Helper()
+
+		events, err := f.contract.GetEvents(f.as(processId), assetId, sinceSeq)
+
+		if err != nil {
+			t.Fatalf("GetEvents: %s", err)
+		}
+
+		replayed := []string{}
+
+		for _, event := range events {
+			replayed = append(replayed, event.Name)
+		}
+
+		return strings.Join(replayed, ",")
+	}
+
+	for sinceSeq, expected := range map[int]string{0: "First,Second,Third", 1: "Second,Third", 3: ""} {
+		if got := names(sinceSeq); got != expected {
+			t.Fatalf("expected %q since %d, got %q", expected, sinceSeq, got)
+		}
+	}
+}
+
+func TestQueryAssetsByTag(t *testing.T) {
+	f := newFixture(t)
+
+	tagged := func(tags map[string]string) string {
+		request := assetRequest()
+		request.Tags = tags
+
+		return f.init(request)
+	}
+
+	southId := tagged(map[string]string{"region": "south", "category": "fragile"})
+	tagged(map[string]string{"region": "north", "category": "fragile"})
+	f.init(assetRequest())
+
+	if tags := f.asset(southId).Tags; tags["region"] != "south" || tags["category"] != "fragile" {
+		t.Fatalf("expected the tags set at Init, got %v", tags)
+	}
+
+	assets, err := f.contract.QueryAssetsByTag(f.as(applicationId), "region", "south")
+
+	if err != nil || len(assets) != 1 || assets[0].Id != southId {
+		t.Fatalf("expected only the south asset, got %v, %v", assets, err)
+	}
+
+	if assets, _ := f.contract.QueryAssetsByTag(f.as(applicationId), "category", "fragile"); len(assets) != 2 {
+		t.Fatalf("expected both fragile assets, got %d", len(assets))
+	}
+
+	for _, key := range []string{"", "a.b", "$gt"} {
+		request := assetRequest()
+		request.Tags = map[string]string{key: "x"}
+
+		if _, err := f.contract.Init(f.as(applicationId), request); err == nil || err.Error() != fmt.Sprintf("invalid tag key: %q", key) {
+			t.Fatalf("expected the tag key %q to be rejected at Init, got %v", key, err)
+		}
+
+		if _, err := f.contract.QueryAssetsByTag(f.as(applicationId), key, "x"); err == nil {
+			t.Fatalf("expected the tag key %q to be rejected in the query", key)
+		}
+	}
+}
+
+func TestGetClientMSP(t *testing.T) {
+	f := newFixture(t)
+
+	for _, mspId := range []string{defaultMSP, "CarrierMSP"} {
+		clientMSP, err := f.contract.GetClientMSP(f.asMSP(processId, mspId))
+
+		if err != nil || clientMSP != mspId {
+			t.Fatalf("expected %s, got %s, %v", mspId, clientMSP, err)
+		}
+	}
+
+	ctx := f.asMSP(processId, "CarrierMSP")
+
+	if fromOrg, err := f.contract.isFromOrg(ctx, "CarrierMSP"); err != nil || !fromOrg {
+		t.Fatalf("expected the caller to be from CarrierMSP, got %t, %v", fromOrg, err)
+	}
+
+	if fromOrg, err := f.contract.isFromOrg(ctx, defaultMSP); err != nil || fromOrg {
+		t.Fatalf("expected the caller to not be from %s, got %t, %v", defaultMSP, fromOrg, err)
+	}
+}
+
+func TestGetAssetsCreatedBetween(t *testing.T) {
+	f := newFixture(t)
+
+	f.init(assetRequest())
+
+	f.advance(24 * time.Hour)
+
+	first := f.init(assetRequest())
+
+	f.advance(24 * time.Hour)
+
+	second := f.init(assetRequest())
+
+	f.advance(24 * time.Hour)
+
+	f.init(assetRequest())
+
+	assets, err := f.contract.GetAssetsCreatedBetween(f.as(applicationId), "2024-06-02T12:00:00Z", "2024-06-03T12:00:00Z")
+
+	if err != nil {
+		t.Fatalf("GetAssetsCreatedBetween: %s", err)
+	}
+
+	ids := []string{}
+
+	for _, asset := range assets {
+		ids = append(ids, asset.Id)
+	}
+
+	sort.Strings(ids)
+
+	expected := []string{first, second}
+	sort.Strings(expected)
+
+	if strings.Join(ids, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected the assets created inside the inclusive window %v, got %v", expected, ids)
+	}
+
+	_, err = f.contract.GetAssetsCreatedBetween(f.as(applicationId), "2024-06-03T00:00:00Z", "2024-06-02T00:00:00Z")
+
+	if err == nil || err.Error() != "end must not be before start" {
+		t.Fatalf("expected an inverted window to be rejected, got %v", err)
+	}
+}
+
+type schemaNode struct {
+	Type                 string                `json:"type"`
+	Properties           map[string]schemaNode `json:"properties"`
+	Required             []string              `json:"required"`
+	AdditionalProperties interface{}           `json:"additionalProperties"`
+}
+
+func TestGetContractJSONSchema(t *testing.T) {
+	f := newFixture(t)
+
+	schemaJSON, err := f.contract.GetContractJSONSchema(f.as(outsiderId))
+
+	if err != nil {
+		t.Fatalf("GetContractJSONSchema: %s", err)
+	}
+
+	var schema struct {
+		AssetRequest schemaNode            `json:"assetRequest"`
+		Clauses      map[string]schemaNode `json:"clauses"`
+	}
+
+	if err := json.Unmarshal([]byte(schemaJSON), &schema); err != nil {
+		t.Fatalf("expected the schema to be JSON, got %s", err)
+	}
+
+	assetRequest := schema.AssetRequest
+
+	if assetRequest.Properties["beginDate"].Type != "string" || assetRequest.Properties["parties"].Properties["application"].Properties["id"].Type != "string" {
+		t.Fatalf("expected the AssetRequest fields, got %+v", assetRequest.Properties)
+	}
+
+	if strings.Join(assetRequest.Required, ",") != "beginDate,dueDate,parties" {
+		t.Fatalf("expected the fields without omitempty to be required, got %v", assetRequest.Required)
+	}
+
+	args, ok := schema.Clauses["RightRequestDelivery"]
+
+	if !ok || args.AdditionalProperties != false {
+		t.Fatalf("expected a closed schema for the clause args, got %+v", args)
+	}
+
+	for _, field := range []string{"numberOfAddresses", "weight", "productValue"} {
+		if args.Properties[field].Type != "integer" {
+			t.Fatalf("expected %s to be an integer, got %+v", field, args.Properties[field])
+		}
+	}
+
+	if args.Properties["currency"].Type != "string" || strings.Contains(strings.Join(args.Required, ","), "currency") {
+		t.Fatalf("expected currency to be an optional string, got %+v", args)
+	}
+}
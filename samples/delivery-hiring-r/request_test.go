@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestLifecycle(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	result, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	if err != nil {
+		t.Fatalf("ClauseRightRequestDelivery: %s", err)
+	}
+
+	request, err := f.contract.getRequest(f.as(processId), assetId, result.RequestId)
+
+	if err != nil || request.State != RequestPending {
+		t.Fatalf("expected a new request to be %s, got %+v, %v", RequestPending, request, err)
+	}
+
+	for _, state := range []string{RequestApproved, RequestFulfilled, RequestCompensated} {
+		if err := f.contract.UpdateRequestState(f.as(processId), assetId, result.RequestId, state); err != nil {
+			t.Fatalf("transition to %s: %s", state, err)
+		}
+	}
+
+	request, _ = f.contract.getRequest(f.as(processId), assetId, result.RequestId)
+
+	if request.State != RequestCompensated {
+		t.Fatalf("expected the request to be %s, got %s", RequestCompensated, request.State)
+	}
+}
+
+func TestRequestLifecycleRejectsIllegalTransition(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	result, _ := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	for _, state := range []string{RequestApproved, RequestFulfilled} {
+		if err := f.contract.UpdateRequestState(f.as(processId), assetId, result.RequestId, state); err != nil {
+			t.Fatalf("transition to %s: %s", state, err)
+		}
+	}
+
+	err := f.contract.UpdateRequestState(f.as(processId), assetId, result.RequestId, RequestPending)
+
+	if err == nil || !strings.Contains(err.Error(), "invalid request state transition: FULFILLED -> PENDING") {
+		t.Fatalf("expected FULFILLED -> PENDING to be rejected, got %v", err)
+	}
+
+	err = f.contract.UpdateRequestState(f.as(outsiderId), assetId, result.RequestId, RequestCompensated)
+
+	if err == nil {
+		t.Fatalf("expected a non-party to be rejected")
+	}
+}
+
+func TestRequestLifecycleCanBeDisabled(t *testing.T) {
+	f := newFixture(t)
+
+	enforceRequestLifecycle = false
+	t.Cleanup(func() { enforceRequestLifecycle = true })
+
+	assetId := f.signed(assetRequest())
+
+	result, _ := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	if err := f.contract.UpdateRequestState(f.as(processId), assetId, result.RequestId, RequestCompensated); err != nil {
+		t.Fatalf("expected any transition when the lifecycle is not enforced, got %s", err)
+	}
+}
+
+func TestRequestsAreStoredUnderCompositeKeys(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+	otherId := f.signed(assetRequest())
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs()); err != nil {
+		t.Fatalf("ClauseRightRequestDelivery: %s", err)
+	}
+
+	assetAsBytes := string(f.stub.State[assetId])
+
+	ids := map[string]bool{}
+
+	for i := 0; i < 2; i++ {
+		result, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+		if err != nil {
+			t.Fatalf("ClauseRightRequestDelivery: %s", err)
+		}
+
+		ids[result.RequestId] = true
+	}
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.as(processId), otherId, validArgs()); err != nil {
+		t.Fatalf("ClauseRightRequestDelivery: %s", err)
+	}
+
+	if string(f.stub.State[assetId]) != assetAsBytes {
+		t.Fatalf("expected later clause calls to leave the asset document untouched")
+	}
+
+	requests, err := f.contract.GetRequestsByAsset(f.as(processId), assetId)
+
+	if err != nil {
+		t.Fatalf("GetRequestsByAsset: %s", err)
+	}
+
+	if len(requests) != 3 {
+		t.Fatalf("expected 3 requests for the asset, got %d", len(requests))
+	}
+
+	for _, request := range requests {
+		key, _ := f.stub.CreateCompositeKey(requestObjectType, []string{assetId, request.Id})
+
+		if f.stub.State[key] == nil {
+			t.Fatalf("expected request %s under its composite key", request.Id)
+		}
+
+		delete(ids, request.Id)
+	}
+
+	if len(ids) != 0 {
+		t.Fatalf("requests missing from the partial key query: %v", ids)
+	}
+}
+
+func TestFailedRequestIsStoredWithReason(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	result, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, RightRequestDeliveryArgs{NumberOfAddresses: 2, Weight: 100, ProductValue: 100})
+
+	if err != nil {
+		t.Fatalf("ClauseRightRequestDelivery: %s", err)
+	}
+
+	request, err := f.contract.getRequest(f.as(processId), assetId, result.RequestId)
+
+	if err != nil {
+		t.Fatalf("getRequest: %s", err)
+	}
+
+	if request.Valid || request.FailureReason != "Request operation did not meet all requirements: numberOfAddresses" {
+		t.Fatalf("expected the failed request to be stored with its reason, got %+v", request)
+	}
+
+	result, _ = f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+	request, _ = f.contract.getRequest(f.as(processId), assetId, result.RequestId)
+
+	if !request.Valid || request.FailureReason != "" {
+		t.Fatalf("expected a valid request without a reason, got %+v", request)
+	}
+}
+
+func TestGetRequestsBetween(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	first, _ := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	f.advance(time.Hour)
+
+	second, _ := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	f.advance(time.Hour)
+
+	f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	requests, err := f.contract.GetRequestsBetween(f.as(processId), assetId, "2024-06-01T12:00:00Z", "2024-06-01T13:00:00Z")
+
+	if err != nil {
+		t.Fatalf("GetRequestsBetween: %s", err)
+	}
+
+	ids := map[string]bool{}
+
+	for _, request := range requests {
+		ids[request.Id] = true
+	}
+
+	if len(requests) != 2 || !ids[first.RequestId] || !ids[second.RequestId] {
+		t.Fatalf("expected the two requests inside the inclusive range, got %v", ids)
+	}
+
+	_, err = f.contract.GetRequestsBetween(f.as(processId), assetId, "2024-06-02T00:00:00Z", "2024-06-01T00:00:00Z")
+
+	if err == nil || err.Error() != "start date greater than end date" {
+		t.Fatalf("expected an inverted range to be rejected, got %v", err)
+	}
+}
+
+func TestRequestStoresSubmittedArgs(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	args := RightRequestDeliveryArgs{NumberOfAddresses: 1, Weight: 98, ProductValue: 1234}
+
+	result, _ := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, args)
+	request, _ := f.contract.getRequest(f.as(processId), assetId, result.RequestId)
+
+	var stored RightRequestDeliveryArgs
+
+	if err := json.Unmarshal(request.Args, &stored); err != nil {
+		t.Fatalf("expected the args to be stored as JSON, got %s", err)
+	}
+
+	if stored != args {
+		t.Fatalf("expected the stored args %+v, got %+v", args, stored)
+	}
+}
+
+func TestGetAllRequestsIsSortedAndComplete(t *testing.T) {
+	f := newFixture(t)
+
+	f.putLegacyAsset("legacy", strings.Replace(legacyAssetJSON, `"isSigned": true,`, `"isSigned": true,
+	"requests": {"stored-in-asset": {"clause": "RightRequestDelivery", "clientId": "process-id", "createdAt": "2024-03-01T00:00:00Z", "valid": true}},`, 1))
+
+	f.advance(time.Hour)
+
+	late, _ := f.contract.ClauseRightRequestDelivery(f.as(processId), "legacy", validArgs())
+
+	f.advance(-30 * time.Minute)
+
+	early, _ := f.contract.ClauseRightRequestDelivery(f.as(applicationId), "legacy", RightRequestDeliveryArgs{NumberOfAddresses: 2, Weight: 100, ProductValue: 100})
+
+	requests, err := f.contract.GetAllRequests(f.as(processId), "legacy")
+
+	if err != nil {
+		t.Fatalf("GetAllRequests: %s", err)
+	}
+
+	ids := []string{}
+
+	for _, request := range requests {
+		ids = append(ids, request.Id)
+	}
+
+	expected := []string{"stored-in-asset", early.RequestId, late.RequestId}
+
+	if strings.Join(ids, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected the requests ordered by creation %v, got %v", expected, ids)
+	}
+
+	if requests[1].ClientId != applicationId || requests[1].Valid || len(requests[1].Args) == 0 {
+		t.Fatalf("expected the failed request with its client and args, got %+v", requests[1])
+	}
+
+	if requests[2].ClientId != processId || !requests[2].Valid || !requests[2].CreatedAt.Equal(f.now.Add(30*time.Minute)) {
+		t.Fatalf("expected the valid request with its client and time, got %+v", requests[2])
+	}
+}
+
+func TestGetSLACompliance(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	compliance, err := f.contract.GetSLACompliance(f.as(processId), assetId)
+
+	if err != nil || compliance != 1.0 {
+		t.Fatalf("expected full compliance without requests, got %v, %v", compliance, err)
+	}
+
+	invalid := RightRequestDeliveryArgs{NumberOfAddresses: 2, Weight: 100, ProductValue: 100}
+
+	for _, args := range []RightRequestDeliveryArgs{validArgs(), invalid, validArgs(), validArgs()} {
+		if _, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, args); err != nil {
+			t.Fatalf("ClauseRightRequestDelivery: %s", err)
+		}
+
+		f.advance(time.Minute)
+	}
+
+	if compliance, _ = f.contract.GetSLACompliance(f.as(processId), assetId); compliance != 0.75 {
+		t.Fatalf("expected 3 of 4 requests to comply, got %v", compliance)
+	}
+
+	if _, err := f.contract.GetSLACompliance(f.as(processId), "missing"); err == nil {
+		t.Fatalf("expected a missing asset to be rejected")
+	}
+}
+
+func TestRequestQueriesIncludeLegacyRequests(t *testing.T) {
+	f := newFixture(t)
+
+	f.putLegacyAsset("legacy", strings.Replace(legacyAssetJSON, `"isSigned": true,`, `"isSigned": true,
+	"requests": {"stored-in-asset": {"clause": "RightRequestDelivery", "clientId": "process-id", "createdAt": "2024-03-01T00:00:00Z", "valid": false}},`, 1))
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.as(processId), "legacy", validArgs()); err != nil {
+		t.Fatalf("ClauseRightRequestDelivery: %s", err)
+	}
+
+	summary, err := f.contract.GetContractSummary(f.as(processId), "legacy")
+
+	if err != nil || summary.RequestCount != 2 {
+		t.Fatalf("expected the summary to count the legacy request, got %+v, %v", summary, err)
+	}
+
+	between, err := f.contract.GetRequestsBetween(f.as(processId), "legacy", "2024-02-01T00:00:00Z", "2024-04-01T00:00:00Z")
+
+	if err != nil || len(between) != 1 || between[0].Id != "stored-in-asset" {
+		t.Fatalf("expected the legacy request in its range, got %+v, %v", between, err)
+	}
+
+	compliance, err := f.contract.GetSLACompliance(f.as(processId), "legacy")
+
+	if err != nil || compliance != 0.5 {
+		t.Fatalf("expected the legacy request to count towards compliance, got %f, %v", compliance, err)
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSignatureLogKeepsSigningOrder(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.init(assetRequest())
+
+	if err := f.contract.Sign(f.as(processId), assetId, ""); err != nil {
+		t.Fatalf("Sign: %s", err)
+	}
+
+	f.advance(time.Hour)
+
+	if err := f.contract.Sign(f.as(applicationId), assetId, ""); err != nil {
+		t.Fatalf("Sign: %s", err)
+	}
+
+	log := f.asset(assetId).SignatureLog
+
+	if len(log) != 2 {
+		t.Fatalf("expected 2 signature entries, got %d", len(log))
+	}
+
+	if log[0].ClientId != processId || log[0].Role != RoleProcess || log[1].ClientId != applicationId || log[1].Role != RoleApplication {
+		t.Fatalf("expected the process then the application, got %+v", log)
+	}
+
+	if !log[1].SignedAt.After(log[0].SignedAt) {
+		t.Fatalf("expected the entries in signing order, got %s then %s", log[0].SignedAt, log[1].SignedAt)
+	}
+}
+
+func TestGetSignatureStatus(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.init(assetRequest())
+
+	if err := f.contract.Sign(f.as(processId), assetId, "sig-ref"); err != nil {
+		t.Fatalf("Sign: %s", err)
+	}
+
+	statuses, err := f.contract.GetSignatureStatus(f.as(applicationId), assetId)
+
+	if err != nil {
+		t.Fatalf("GetSignatureStatus: %s", err)
+	}
+
+	if len(statuses) != 2 {
+		t.Fatalf("expected a status per party, got %d", len(statuses))
+	}
+
+	application, process := statuses[0], statuses[1]
+
+	if application.Role != RoleApplication || application.Id != applicationId || application.IsSigned || !application.SignatureDate.IsZero() {
+		t.Fatalf("expected the application to be unsigned, got %+v", application)
+	}
+
+	if process.Role != RoleProcess || process.Id != processId || !process.IsSigned || !process.SignatureDate.Equal(f.now) || process.SignatureRef != "sig-ref" {
+		t.Fatalf("expected the process to be signed, got %+v", process)
+	}
+}
+
+func TestSignBatchReportsEachAsset(t *testing.T) {
+	f := newFixture(t)
+
+	unsigned := f.init(assetRequest())
+	signed := f.init(assetRequest())
+
+	f.contract.Sign(f.as(processId), signed, "")
+
+	foreign := assetRequest()
+	foreign.Parties.Application.Id = "other-application-id"
+	foreign.Parties.Process.Id = "other-process-id"
+
+	foreignId, err := f.contract.Init(f.as("other-application-id"), foreign)
+
+	if err != nil {
+		t.Fatalf("Init: %s", err)
+	}
+
+	cancelled := f.init(assetRequest())
+
+	f.contract.Cancel(f.as(applicationId), cancelled, "")
+
+	results, err := f.contract.SignBatch(f.as(processId), []string{unsigned, signed, foreignId, cancelled, "missing"})
+
+	if err != nil {
+		t.Fatalf("SignBatch: %s", err)
+	}
+
+	expected := map[string]string{
+		unsigned:  SignResultSigned,
+		signed:    SignResultAlreadySigned,
+		foreignId: SignResultNotAParty,
+		cancelled: SignResultError,
+		"missing": SignResultError,
+	}
+
+	for assetId, status := range expected {
+		if results[assetId].Status != status {
+			t.Fatalf("expected %s for %s, got %+v", status, assetId, results[assetId])
+		}
+	}
+
+	if results["missing"].Error == "" || results[cancelled].Error != "contract cancelled" {
+		t.Fatalf("expected the failures to carry their error, got %+v", results)
+	}
+
+	if !f.asset(unsigned).Parties.Process.IsSigned {
+		t.Fatalf("expected the batch to persist the signature")
+	}
+}
+
+func TestSigningBeforeBeginDate(t *testing.T) {
+	f := newFixture(t)
+
+	request := assetRequest()
+	request.BeginDate = "2024-07-01T00:00:00Z"
+
+	assetId := f.signed(request)
+
+	if !f.asset(assetId).IsSigned {
+		t.Fatalf("expected signing before the begin date to succeed")
+	}
+
+	_, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	if err == nil || err.Error() != "the current date is before the start date" {
+		t.Fatalf("expected the clause to wait for the begin date, got %v", err)
+	}
+
+	expiredId := f.init(assetRequest())
+
+	f.now = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	err = f.contract.Sign(f.as(applicationId), expiredId, "")
+
+	if err == nil || err.Error() != "asset expired. The current date is after the due date" {
+		t.Fatalf("expected signing after the due date to be rejected, got %v", err)
+	}
+}
+
+func TestSignatureRefIsPersisted(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.init(assetRequest())
+
+	if err := f.contract.Sign(f.as(applicationId), assetId, "sha256:4f2a"); err != nil {
+		t.Fatalf("Sign: %s", err)
+	}
+
+	if err := f.contract.Sign(f.as(processId), assetId, ""); err != nil {
+		t.Fatalf("Sign: %s", err)
+	}
+
+	if ref := f.asset(assetId).Parties.Application.SignatureRef; ref != "sha256:4f2a" {
+		t.Fatalf("expected the ref on the party, got %q", ref)
+	}
+
+	statuses, _ := f.contract.GetSignatureStatus(f.as(applicationId), assetId)
+
+	if statuses[0].SignatureRef != "sha256:4f2a" || statuses[1].SignatureRef != "" {
+		t.Fatalf("expected the ref in the signature status, got %+v", statuses)
+	}
+}
+
+func TestAmendmentInvalidatesSignatures(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	for _, id := range []string{applicationId, processId} {
+		if _, err := f.contract.ExtendDueDate(f.as(id), assetId, "2025-06-30T00:00:00Z"); err != nil {
+			t.Fatalf("ExtendDueDate: %s", err)
+		}
+	}
+
+	asset := f.asset(assetId)
+
+	if asset.IsSigned || asset.Parties.Application.IsSigned || !asset.Parties.Process.SignatureDate.IsZero() {
+		t.Fatalf("expected the extension to reset the signatures, got %+v", asset.Parties)
+	}
+
+	_, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs())
+
+	if err == nil || err.Error() != "asset is not signed" {
+		t.Fatalf("expected clauses to wait for the re-signing, got %v", err)
+	}
+
+	for _, id := range []string{applicationId, processId} {
+		if err := f.contract.Sign(f.as(id), assetId, ""); err != nil {
+			t.Fatalf("Sign: %s", err)
+		}
+	}
+
+	if _, err := f.contract.ClauseRightRequestDelivery(f.as(processId), assetId, validArgs()); err != nil {
+		t.Fatalf("expected clauses once re-signed, got %s", err)
+	}
+}
+
+func TestReplacePartyIdentityKeepsSignatures(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	for _, id := range []string{processId, applicationId} {
+		if _, err := f.contract.ReplacePartyIdentity(f.as(id), assetId, RoleProcess, "process-id-2025"); err != nil {
+			t.Fatalf("ReplacePartyIdentity as %s: %s", id, err)
+		}
+	}
+
+	if !f.asset(assetId).IsSigned {
+		t.Fatalf("expected an identity rotation to keep the signatures")
+	}
+}
+
+func TestPartyAndAssetJSONKeysAreCamelCase(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	var document struct {
+		Parties struct {
+			Application map[string]json.RawMessage `json:"application"`
+		} `json:"parties"`
+	}
+
+	var top map[string]json.RawMessage
+
+	json.Unmarshal(f.stub.State[assetId], &document)
+	json.Unmarshal(f.stub.State[assetId], &top)
+
+	for _, key := range []string{"id", "name", "isSigned", "signatureDate", "signatureRef"} {
+		if _, ok := document.Parties.Application[key]; !ok {
+			t.Fatalf("expected the party key %q, got %v", key, document.Parties.Application)
+		}
+	}
+
+	for _, key := range []string{"id", "parties", "beginDate", "dueDate", "isSigned", "createdAt"} {
+		if _, ok := top[key]; !ok {
+			t.Fatalf("expected the asset key %q", key)
+		}
+	}
+
+	for _, key := range []string{"SignatureDate", "IsSigned", "BeginDate"} {
+		if _, ok := document.Parties.Application[key]; ok {
+			t.Fatalf("expected no Go-cased party key %q", key)
+		}
+
+		if _, ok := top[key]; ok {
+			t.Fatalf("expected no Go-cased asset key %q", key)
+		}
+	}
+}
+
+func TestSignRejectsTerminatedAndCancelledAssets(t *testing.T) {
+	f := newFixture(t)
+
+	terminatedId := f.init(assetRequest())
+
+	f.contract.Sign(f.as(applicationId), terminatedId, "")
+
+	for _, id := range []string{applicationId, processId} {
+		if _, err := f.contract.Terminate(f.as(id), terminatedId, "breach"); err != nil {
+			t.Fatalf("Terminate: %s", err)
+		}
+	}
+
+	if err := f.contract.Sign(f.as(processId), terminatedId, ""); err == nil || err.Error() != "contract terminated" {
+		t.Fatalf("expected signing a terminated contract to be rejected, got %v", err)
+	}
+
+	cancelledId := f.init(assetRequest())
+
+	if err := f.contract.Cancel(f.as(applicationId), cancelledId, "no longer needed"); err != nil {
+		t.Fatalf("Cancel: %s", err)
+	}
+
+	if err := f.contract.Sign(f.as(processId), cancelledId, ""); err == nil || err.Error() != "contract cancelled" {
+		t.Fatalf("expected signing a cancelled contract to be rejected, got %v", err)
+	}
+
+	for _, assetId := range []string{terminatedId, cancelledId} {
+		if f.asset(assetId).Parties.Process.IsSigned {
+			t.Fatalf("expected %s to stay unsigned by the process", assetId)
+		}
+	}
+}
+
+func TestGetSignatureStatusBatch(t *testing.T) {
+	f := newFixture(t)
+
+	signedId := f.signed(assetRequest())
+	draftId := f.init(assetRequest())
+
+	results, err := f.contract.GetSignatureStatusBatch(f.as(applicationId), []string{signedId, "missing", draftId})
+
+	if err != nil {
+		t.Fatalf("GetSignatureStatusBatch: %s", err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("expected a result per id, got %d", len(results))
+	}
+
+	signed := results[signedId]
+
+	if signed.Error != "" || len(signed.Signatures) != 2 || !signed.Signatures[0].IsSigned || !signed.Signatures[1].IsSigned {
+		t.Fatalf("expected both parties signed, got %+v", signed)
+	}
+
+	draft := results[draftId]
+
+	if draft.Error != "" || len(draft.Signatures) != 2 || draft.Signatures[0].IsSigned || draft.Signatures[1].IsSigned {
+		t.Fatalf("expected neither party signed, got %+v", draft)
+	}
+
+	if missing := results["missing"]; missing.Error != "asset not found: missing" || missing.Signatures != nil {
+		t.Fatalf("expected an error entry for the missing id, got %+v", missing)
+	}
+}
@@ -0,0 +1,471 @@
+/**
+ * This file is maintained by hand alongside the contract generated by the
+ * Jabuti Transformation Engine. It is not overwritten on regeneration.
+ *
+ * Copyright: Applied Computing Research Group (GCA), Unijuí University, Ijui-RS, Brazil
+ * SPDX-License-Identifier: MIT
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// The coupon terms and the delivery and coupon records are kept under their own composite
+// keys. Penalties are recorded on the generated Asset, in PenaltiesApplied, alongside those of
+// the late-delivery obligation clauses.
+const (
+	couponTermsObjectType = "couponTerms"
+	deliveryObjectType    = "delivery"
+	couponObjectType      = "coupon"
+)
+
+var defaultCouponValiditySeconds = 30 * 24 * 60 * 60
+
+type CouponTerms struct {
+	CouponValue           int       `json:"couponValue"`
+	CouponCurrency        string    `json:"couponCurrency"`
+	CouponValiditySeconds int       `json:"couponValiditySeconds"`
+	DeliveryDeadline      time.Time `json:"deliveryDeadline"`
+	RequiredWeight        int       `json:"requiredWeight"`
+	BasePenalty           int       `json:"basePenalty"`
+}
+
+type CouponTermsRequest struct {
+	Value            int    `json:"value,omitempty" metadata:",optional"`
+	Currency         string `json:"currency,omitempty" metadata:",optional"`
+	ValiditySeconds  int    `json:"validitySeconds,omitempty" metadata:",optional"`
+	DeliveryDeadline string `json:"deliveryDeadline,omitempty" metadata:",optional"`
+	RequiredWeight   int    `json:"requiredWeight,omitempty" metadata:",optional"`
+	BasePenalty      int    `json:"basePenalty,omitempty" metadata:",optional"`
+}
+
+type Coupon struct {
+	Id         string    `json:"id"`
+	DeliveryId string    `json:"deliveryId"`
+	Value      int       `json:"value"`
+	Currency   string    `json:"currency"`
+	IssuedAt   time.Time `json:"issuedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Redeemed   bool      `json:"redeemed"`
+}
+
+type UnderweightDeliveryArgs struct {
+	Weight int `json:"weight"`
+}
+
+type Delivery struct {
+	Id                   string    `json:"id"`
+	ExpectedDeliveryDate time.Time `json:"expectedDeliveryDate"`
+	DeliveredAt          time.Time `json:"deliveredAt"`
+	WasLate              bool      `json:"wasLate"`
+}
+
+func init() {
+	clauseDescriptors = append(clauseDescriptors,
+		ClauseDescriptor{
+			Name: "UnderweightDelivery",
+			Arguments: []ClauseArgument{
+				{Name: "weight", Type: "int"},
+			},
+		},
+	)
+}
+
+func (s *SmartContract) isRolePlayer(id string, asset *Asset, role string) error {
+	party, err := s.partyByRole(asset, role)
+
+	if err != nil {
+		return err
+	}
+
+	if party.Id != id {
+		return fmt.Errorf("only the %s can execute this operation", role)
+	}
+
+	return nil
+}
+
+func (s *SmartContract) putRecord(ctx contractapi.TransactionContextInterface, objectType string, assetId string, id string, record interface{}) error {
+	key, err := ctx.GetStub().CreateCompositeKey(objectType, []string{assetId, id})
+
+	if err != nil {
+		return fmt.Errorf("failed to create %s key: %s", objectType, err.Error())
+	}
+
+	recordAsBytes, err := json.Marshal(record)
+
+	if err != nil {
+		return fmt.Errorf("marshal error: %s", err.Error())
+	}
+
+	return ctx.GetStub().PutState(key, recordAsBytes)
+}
+
+// readRecord returns false when no record is stored under the key.
+func (s *SmartContract) readRecord(ctx contractapi.TransactionContextInterface, objectType string, assetId string, id string, record interface{}) (bool, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(objectType, []string{assetId, id})
+
+	if err != nil {
+		return false, fmt.Errorf("failed to create %s key: %s", objectType, err.Error())
+	}
+
+	recordAsBytes, err := ctx.GetStub().GetState(key)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to read from state: %s", err.Error())
+	}
+
+	if recordAsBytes == nil {
+		return false, nil
+	}
+
+	if err = json.Unmarshal(recordAsBytes, record); err != nil {
+		return false, fmt.Errorf("marshal error: %s", err.Error())
+	}
+
+	return true, nil
+}
+
+func (s *SmartContract) couponTerms(ctx contractapi.TransactionContextInterface, asset *Asset) (*CouponTerms, error) {
+	terms := &CouponTerms{
+		CouponCurrency:        "USD",
+		CouponValiditySeconds: defaultCouponValiditySeconds,
+		DeliveryDeadline:      asset.DueDate,
+		RequiredWeight:        100,
+		BasePenalty:           10,
+	}
+
+	if _, err := s.readRecord(ctx, couponTermsObjectType, asset.Id, asset.Id, terms); err != nil {
+		return nil, err
+	}
+
+	return terms, nil
+}
+
+// ConfigureCoupon overrides the default coupon terms. Like the generated clause config it can
+// only change before the contract is signed.
+func (s *SmartContract) ConfigureCoupon(ctx contractapi.TransactionContextInterface, assetId string, request CouponTermsRequest) error {
+
+	var id string
+	var err error
+	var asset *Asset
+	var terms *CouponTerms
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return err
+	}
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return err
+	}
+
+	if _, err = s.isParty(id, asset); err != nil {
+		return err
+	}
+
+	if err = s.isClauseConfigMutable(asset); err != nil {
+		return err
+	}
+
+	if request.Value < 0 || request.ValiditySeconds < 0 || request.RequiredWeight < 0 || request.BasePenalty < 0 {
+		return fmt.Errorf("coupon terms must not be negative")
+	}
+
+	if terms, err = s.couponTerms(ctx, asset); err != nil {
+		return err
+	}
+
+	if request.Value > 0 {
+		terms.CouponValue = request.Value
+	}
+
+	if request.Currency != "" {
+		terms.CouponCurrency = request.Currency
+	}
+
+	if request.ValiditySeconds > 0 {
+		terms.CouponValiditySeconds = request.ValiditySeconds
+	}
+
+	if request.DeliveryDeadline != "" {
+		if terms.DeliveryDeadline, err = s.string2Time(request.DeliveryDeadline); err != nil {
+			return err
+		}
+	}
+
+	if request.RequiredWeight > 0 {
+		terms.RequiredWeight = request.RequiredWeight
+	}
+
+	if request.BasePenalty > 0 {
+		terms.BasePenalty = request.BasePenalty
+	}
+
+	return s.putRecord(ctx, couponTermsObjectType, assetId, assetId, terms)
+}
+
+// recordDelivery keeps one record per delivery, so a late delivery earns at most one coupon.
+func (s *SmartContract) recordDelivery(ctx contractapi.TransactionContextInterface, asset *Asset, terms *CouponTerms, deliveryId string, deliveryDate time.Time) (bool, error) {
+	if deliveryId == "" {
+		return false, fmt.Errorf("delivery id is required")
+	}
+
+	exists, err := s.readRecord(ctx, deliveryObjectType, asset.Id, deliveryId, &Delivery{})
+
+	if err != nil {
+		return false, err
+	}
+
+	if exists {
+		return false, fmt.Errorf("delivery %s already recorded", deliveryId)
+	}
+
+	delivery := Delivery{
+		Id:                   deliveryId,
+		ExpectedDeliveryDate: terms.DeliveryDeadline,
+		DeliveredAt:          deliveryDate,
+		WasLate:              deliveryDate.After(terms.DeliveryDeadline),
+	}
+
+	if err = s.putRecord(ctx, deliveryObjectType, asset.Id, deliveryId, delivery); err != nil {
+		return false, err
+	}
+
+	if delivery.WasLate {
+		if err = s.issueCoupon(ctx, asset, terms, deliveryId); err != nil {
+			return false, err
+		}
+	}
+
+	return delivery.WasLate, nil
+}
+
+// ClauseUnderweightDelivery returns the penalty in basis points.
+func (s *SmartContract) ClauseUnderweightDelivery(ctx contractapi.TransactionContextInterface, assetId string, args UnderweightDeliveryArgs) (int, error) {
+
+	var id string
+	var err error
+	var asset *Asset
+	var terms *CouponTerms
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return 0, err
+	}
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return 0, err
+	}
+
+	if err = s.isRolePlayer(id, asset, RoleApplication); err != nil {
+		return 0, err
+	}
+
+	if err = s.canExecuteClause(asset); err != nil {
+		return 0, err
+	}
+
+	if err = s.checkDependencies(asset, "UnderweightDelivery"); err != nil {
+		return 0, err
+	}
+
+	if args.Weight <= 0 {
+		return 0, fmt.Errorf("weight must be positive")
+	}
+
+	if terms, err = s.couponTerms(ctx, asset); err != nil {
+		return 0, err
+	}
+
+	penalty := 0
+
+	// BasePenalty is a percentage; the penalty is computed in basis points and rounded half
+	// up, so a small shortfall still owes its share instead of being truncated to zero
+	if args.Weight < terms.RequiredWeight {
+		shortfall := (terms.RequiredWeight - args.Weight) * terms.BasePenalty * 100
+		penalty = (2*shortfall + terms.RequiredWeight) / (2 * terms.RequiredWeight)
+	}
+
+	if penalty > 0 {
+		asset.PenaltiesApplied = append(asset.PenaltiesApplied, Penalty{
+			Clause:              "UnderweightDelivery",
+			DiscountBasisPoints: penalty,
+			CreatedAt:           nowFunc().UTC(),
+		})
+	}
+
+	s.markClauseFired(asset, "UnderweightDelivery")
+
+	if err = s.putState(ctx, assetId, asset); err != nil {
+		return 0, err
+	}
+
+	return penalty, nil
+}
+
+// issueCoupon refuses to issue a coupon until ConfigureCoupon has set its value, since there
+// is no sensible default amount to owe.
+func (s *SmartContract) issueCoupon(ctx contractapi.TransactionContextInterface, asset *Asset, terms *CouponTerms, deliveryId string) error {
+	if terms.CouponValue <= 0 {
+		return fmt.Errorf("coupon value is not configured")
+	}
+
+	issuedAt := nowFunc().UTC()
+
+	coupon := Coupon{
+		Id:         uuid.New().String(),
+		DeliveryId: deliveryId,
+		Value:      terms.CouponValue,
+		Currency:   terms.CouponCurrency,
+		IssuedAt:   issuedAt,
+		ExpiresAt:  issuedAt.Add(time.Duration(terms.CouponValiditySeconds) * time.Second),
+	}
+
+	return s.putRecord(ctx, couponObjectType, asset.Id, coupon.Id, coupon)
+}
+
+func (s *SmartContract) RedeemCoupon(ctx contractapi.TransactionContextInterface, assetId string, couponId string) error {
+
+	var id string
+	var err error
+	var asset *Asset
+	var exists bool
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return err
+	}
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return err
+	}
+
+	if _, err = s.isParty(id, asset); err != nil {
+		return err
+	}
+
+	coupon := Coupon{}
+
+	if exists, err = s.readRecord(ctx, couponObjectType, assetId, couponId, &coupon); err != nil {
+		return err
+	}
+
+	if !exists {
+		return fmt.Errorf("no coupon found for %s", couponId)
+	}
+
+	if coupon.Redeemed {
+		return fmt.Errorf("coupon %s already redeemed", couponId)
+	}
+
+	if nowFunc().UTC().After(coupon.ExpiresAt) {
+		return fmt.Errorf("coupon %s expired at %s", couponId, coupon.ExpiresAt.Format(time.RFC3339))
+	}
+
+	coupon.Redeemed = true
+
+	return s.putRecord(ctx, couponObjectType, assetId, couponId, coupon)
+}
+
+// RecordDelivery records a delivery against the configured delivery deadline and issues a
+// coupon when it is late. The caller names the delivery, so a contract can record several
+// deliveries while the same one still cannot earn a second coupon.
+func (s *SmartContract) RecordDelivery(ctx contractapi.TransactionContextInterface, assetId string, deliveryId string, deliveredAt string) (bool, error) {
+
+	var id string
+	var err error
+	var asset *Asset
+	var terms *CouponTerms
+	var deliveryDate time.Time
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return false, err
+	}
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return false, err
+	}
+
+	if _, err = s.isParty(id, asset); err != nil {
+		return false, err
+	}
+
+	if err = s.canExecuteClause(asset); err != nil {
+		return false, err
+	}
+
+	if deliveryDate, err = s.string2Time(deliveredAt); err != nil {
+		return false, err
+	}
+
+	if terms, err = s.couponTerms(ctx, asset); err != nil {
+		return false, err
+	}
+
+	return s.recordDelivery(ctx, asset, terms, deliveryId, deliveryDate)
+}
+
+func (s *SmartContract) GetPenalties(ctx contractapi.TransactionContextInterface, assetId string) ([]Penalty, error) {
+	asset, err := s.QueryAsset(ctx, assetId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if asset.PenaltiesApplied == nil {
+		return []Penalty{}, nil
+	}
+
+	return asset.PenaltiesApplied, nil
+}
+
+func (s *SmartContract) GetCoupons(ctx contractapi.TransactionContextInterface, assetId string) ([]Coupon, error) {
+	coupons := []Coupon{}
+
+	err := s.forEachRecord(ctx, couponObjectType, assetId, func(recordAsBytes []byte) error {
+		coupon := Coupon{}
+
+		if err := json.Unmarshal(recordAsBytes, &coupon); err != nil {
+			return fmt.Errorf("marshal error: %s", err.Error())
+		}
+
+		coupons = append(coupons, coupon)
+
+		return nil
+	})
+
+	return coupons, err
+}
+
+func (s *SmartContract) forEachRecord(ctx contractapi.TransactionContextInterface, objectType string, assetId string, fn func(recordAsBytes []byte) error) error {
+	if err := s.checkReadAccess(ctx, assetId); err != nil {
+		return err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(objectType, []string{assetId})
+
+	if err != nil {
+		return fmt.Errorf("failed to read from state: %s", err.Error())
+	}
+
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		result, err := resultsIterator.Next()
+
+		if err != nil {
+			return fmt.Errorf("failed to read from state: %s", err.Error())
+		}
+
+		if err = fn(result.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// tieredRequest configures the discount policy of the contract: 3% for late purchases
+// between 100 and 300 USD and 5% for late purchases above 300 USD.
+func tieredRequest() AssetRequest {
+	request := assetRequest()
+	request.ObligationPurchasesBetween100USD300USD.DiscountPercentage = 3
+	request.ObligationPurchasesGreatherThan300USD.DiscountPercentage = 5
+
+	return request
+}
+
+func TestLateDeliveryDiscountFollowsThePurchaseBand(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(tieredRequest())
+
+	onTime := ObligationPurchasesBetween100USD300USDArgs{TotalPurchaseAmount: 150, DeliveryDate: 10, ExpectedDate: 10}
+
+	if result, err := f.contract.ClauseObligationPurchasesBetween100USD300USD(f.as(applicationId), assetId, onTime); err != nil || result.Valid {
+		t.Fatalf("expected an on-time delivery to owe no discount, got %+v, %v", result, err)
+	}
+
+	outOfBand := ObligationPurchasesBetween100USD300USDArgs{TotalPurchaseAmount: 500, DeliveryDate: 11, ExpectedDate: 10}
+
+	if result, err := f.contract.ClauseObligationPurchasesBetween100USD300USD(f.as(applicationId), assetId, outOfBand); err != nil || result.Valid {
+		t.Fatalf("expected a purchase above the band to owe no discount in it, got %+v, %v", result, err)
+	}
+
+	if penalties, _ := f.contract.GetPenalties(f.as(applicationId), assetId); len(penalties) != 0 {
+		t.Fatalf("expected no penalty yet, got %+v", penalties)
+	}
+
+	late := ObligationPurchasesBetween100USD300USDArgs{TotalPurchaseAmount: 150, DeliveryDate: 11, ExpectedDate: 10}
+
+	if result, err := f.contract.ClauseObligationPurchasesBetween100USD300USD(f.as(applicationId), assetId, late); err != nil || !result.Valid {
+		t.Fatalf("expected a late purchase in the band to owe a discount, got %+v, %v", result, err)
+	}
+
+	lateAbove := ObligationPurchasesGreatherThan300USDArgs{TotalPurchaseAmount: 500, DeliveryDate: 11, ExpectedDate: 10}
+
+	if result, err := f.contract.ClauseObligationPurchasesGreatherThan300USD(f.as(applicationId), assetId, lateAbove); err != nil || !result.Valid {
+		t.Fatalf("expected a late purchase above 300 USD to owe a discount, got %+v, %v", result, err)
+	}
+
+	penalties, err := f.contract.GetPenalties(f.as(applicationId), assetId)
+
+	if err != nil || len(penalties) != 2 {
+		t.Fatalf("expected two penalties, got %+v, %v", penalties, err)
+	}
+
+	if penalties[0].Clause != "ObligationPurchasesBetween100USD300USD" || penalties[0].DiscountBasisPoints != 300 {
+		t.Fatalf("expected a 3%% discount for the 100-300 USD band, got %+v", penalties[0])
+	}
+
+	if penalties[1].Clause != "ObligationPurchasesGreatherThan300USD" || penalties[1].DiscountBasisPoints != 500 {
+		t.Fatalf("expected a 5%% discount above 300 USD, got %+v", penalties[1])
+	}
+}
+
+func TestLateDeliveryWithoutConfiguredDiscountRecordsNoPenalty(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	late := ObligationPurchasesGreatherThan300USDArgs{TotalPurchaseAmount: 500, DeliveryDate: 11, ExpectedDate: 10}
+
+	if result, err := f.contract.ClauseObligationPurchasesGreatherThan300USD(f.as(applicationId), assetId, late); err != nil || !result.Valid {
+		t.Fatalf("ClauseObligationPurchasesGreatherThan300USD: %+v, %v", result, err)
+	}
+
+	if penalties, _ := f.contract.GetPenalties(f.as(applicationId), assetId); len(penalties) != 0 {
+		t.Fatalf("expected no penalty without a configured discount, got %+v", penalties)
+	}
+
+	request := assetRequest()
+	request.ObligationPurchasesBetween100USD300USD.DiscountPercentage = 101
+
+	if _, err := f.contract.Init(f.as(applicationId), request); err == nil || err.Error() != "discount percentage must be between 0 and 100" {
+		t.Fatalf("expected a discount above 100%% to be rejected, got %v", err)
+	}
+}
+
+func TestUnderweightDeliveryPenaltyIsProportional(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	for weight, expected := range map[int]int{100: 0, 120: 0, 95: 50, 50: 500} {
+		penalty, err := f.contract.ClauseUnderweightDelivery(f.as(applicationId), assetId, UnderweightDeliveryArgs{Weight: weight})
+
+		if err != nil || penalty != expected {
+			t.Fatalf("expected a penalty of %d for weight %d, got %d, %v", expected, weight, penalty, err)
+		}
+	}
+
+	penalties, _ := f.contract.GetPenalties(f.as(applicationId), assetId)
+
+	if len(penalties) != 2 || penalties[0].DiscountBasisPoints+penalties[1].DiscountBasisPoints != 550 || penalties[0].Clause != "UnderweightDelivery" {
+		t.Fatalf("expected the short deliveries to be penalized, got %+v", penalties)
+	}
+
+	if _, err := f.contract.ClauseUnderweightDelivery(f.as(applicationId), assetId, UnderweightDeliveryArgs{Weight: 0}); err == nil || err.Error() != "weight must be positive" {
+		t.Fatalf("expected a zero weight to be rejected, got %v", err)
+	}
+
+	if _, err := f.contract.ClauseUnderweightDelivery(f.as(processId), assetId, UnderweightDeliveryArgs{Weight: 50}); err == nil {
+		t.Fatalf("expected the process to be rejected")
+	}
+}
+
+func TestUnderweightDeliveryUsesConfiguredTerms(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.init(assetRequest())
+
+	if err := f.contract.ConfigureCoupon(f.as(applicationId), assetId, CouponTermsRequest{RequiredWeight: 200, BasePenalty: 40}); err != nil {
+		t.Fatalf("ConfigureCoupon: %s", err)
+	}
+
+	for _, id := range []string{applicationId, processId} {
+		f.contract.Sign(f.as(id), assetId, "")
+	}
+
+	penalty, err := f.contract.ClauseUnderweightDelivery(f.as(applicationId), assetId, UnderweightDeliveryArgs{Weight: 50})
+
+	if err != nil || penalty != 3000 {
+		t.Fatalf("expected a penalty of 3000 basis points, got %d, %v", penalty, err)
+	}
+}
+
+func TestUnderweightDeliveryKeepsSmallShortfalls(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.init(assetRequest())
+
+	if err := f.contract.ConfigureCoupon(f.as(applicationId), assetId, CouponTermsRequest{RequiredWeight: 3, BasePenalty: 1}); err != nil {
+		t.Fatalf("ConfigureCoupon: %s", err)
+	}
+
+	for _, id := range []string{applicationId, processId} {
+		f.contract.Sign(f.as(id), assetId, "")
+	}
+
+	// a third of a percent rounds down and two thirds round up
+	for weight, expected := range map[int]int{2: 33, 1: 67} {
+		penalty, err := f.contract.ClauseUnderweightDelivery(f.as(applicationId), assetId, UnderweightDeliveryArgs{Weight: weight})
+
+		if err != nil || penalty != expected {
+			t.Fatalf("expected %d basis points for weight %d, got %d, %v", expected, weight, penalty, err)
+		}
+	}
+
+	defaultId := f.signed(assetRequest())
+
+	penalty, err := f.contract.ClauseUnderweightDelivery(f.as(applicationId), defaultId, UnderweightDeliveryArgs{Weight: 99})
+
+	if err != nil || penalty != 10 {
+		t.Fatalf("expected a 1%% shortfall to owe 10 basis points, got %d, %v", penalty, err)
+	}
+}
+
+func TestRecordDeliveryAgainstDeadline(t *testing.T) {
+	f := newFixture(t)
+
+	onTimeId := f.init(assetRequest())
+	lateId := f.init(assetRequest())
+
+	for _, assetId := range []string{onTimeId, lateId} {
+		if err := f.contract.ConfigureCoupon(f.as(applicationId), assetId, CouponTermsRequest{Value: 1000, DeliveryDeadline: "2024-06-10T00:00:00Z"}); err != nil {
+			t.Fatalf("ConfigureCoupon: %s", err)
+		}
+
+		for _, id := range []string{applicationId, processId} {
+			f.contract.Sign(f.as(id), assetId, "")
+		}
+	}
+
+	wasLate, err := f.contract.RecordDelivery(f.as(processId), onTimeId, "d1", "2024-06-10T00:00:00Z")
+
+	if err != nil || wasLate {
+		t.Fatalf("expected a delivery at the deadline to be on time, got %t, %v", wasLate, err)
+	}
+
+	if coupons, _ := f.contract.GetCoupons(f.as(applicationId), onTimeId); len(coupons) != 0 {
+		t.Fatalf("expected no coupon for an on-time delivery, got %d", len(coupons))
+	}
+
+	wasLate, err = f.contract.RecordDelivery(f.as(processId), lateId, "d1", "2024-06-10T00:00:01Z")
+
+	if err != nil || !wasLate {
+		t.Fatalf("expected a delivery past the deadline to be late, got %t, %v", wasLate, err)
+	}
+
+	if coupons, _ := f.contract.GetCoupons(f.as(applicationId), lateId); len(coupons) != 1 || coupons[0].DeliveryId != "d1" {
+		t.Fatalf("expected a coupon for the late delivery, got %+v", coupons)
+	}
+
+	if _, err := f.contract.RecordDelivery(f.as(processId), lateId, "d1", "2024-06-11T00:00:00Z"); err == nil || err.Error() != "delivery d1 already recorded" {
+		t.Fatalf("expected the delivery to be recorded once, got %v", err)
+	}
+
+	if _, err := f.contract.RecordDelivery(f.as(processId), lateId, "d2", "2024-06-11T00:00:00Z"); err != nil {
+		t.Fatalf("expected a second delivery on the same contract, got %s", err)
+	}
+
+	if coupons, _ := f.contract.GetCoupons(f.as(applicationId), lateId); len(coupons) != 2 {
+		t.Fatalf("expected a coupon per late delivery, got %+v", coupons)
+	}
+
+	if _, err := f.contract.RecordDelivery(f.as(processId), lateId, "", "2024-06-11T00:00:00Z"); err == nil || err.Error() != "delivery id is required" {
+		t.Fatalf("expected a missing delivery id to be rejected, got %v", err)
+	}
+
+	if _, err := f.contract.RecordDelivery(f.as(outsiderId), onTimeId, "d2", "2024-06-11T00:00:00Z"); err == nil {
+		t.Fatalf("expected a non-party to be rejected")
+	}
+}
+
+func TestRecordDeliveryDefaultsToTheDueDate(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.signed(assetRequest())
+
+	if _, err := f.contract.RecordDelivery(f.as(processId), assetId, "d1", "not-a-date"); err == nil {
+		t.Fatalf("expected a malformed timestamp to be rejected")
+	}
+
+	wasLate, err := f.contract.RecordDelivery(f.as(processId), assetId, "d1", "2024-06-10T00:00:00Z")
+
+	if err != nil || wasLate {
+		t.Fatalf("expected a delivery before the due date to be on time, got %t, %v", wasLate, err)
+	}
+}
+
+func TestCouponIsIssuedAndRedeemedBeforeExpiry(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.init(assetRequest())
+
+	if err := f.contract.ConfigureCoupon(f.as(applicationId), assetId, CouponTermsRequest{Value: 1500, Currency: "BRL", ValiditySeconds: 3600, DeliveryDeadline: "2024-06-01T10:00:00Z"}); err != nil {
+		t.Fatalf("ConfigureCoupon: %s", err)
+	}
+
+	for _, id := range []string{applicationId, processId} {
+		f.contract.Sign(f.as(id), assetId, "")
+	}
+
+	f.contract.RecordDelivery(f.as(processId), assetId, "d1", "2024-06-01T11:00:00Z")
+
+	coupons, _ := f.contract.GetCoupons(f.as(applicationId), assetId)
+
+	if len(coupons) != 1 {
+		t.Fatalf("expected one coupon, got %d", len(coupons))
+	}
+
+	coupon := coupons[0]
+
+	if coupon.Value != 1500 || coupon.Currency != "BRL" || coupon.Redeemed {
+		t.Fatalf("expected an unredeemed 1500 BRL coupon, got %+v", coupon)
+	}
+
+	if !coupon.IssuedAt.Equal(f.now) || !coupon.ExpiresAt.Equal(f.now.Add(time.Hour)) {
+		t.Fatalf("expected the coupon valid for an hour from %s, got %+v", f.now, coupon)
+	}
+
+	f.advance(time.Hour)
+
+	if err := f.contract.RedeemCoupon(f.as(applicationId), assetId, coupon.Id); err != nil {
+		t.Fatalf("expected the coupon to be redeemable up to its expiry, got %s", err)
+	}
+
+	coupons, _ = f.contract.GetCoupons(f.as(applicationId), assetId)
+
+	if !coupons[0].Redeemed {
+		t.Fatalf("expected the coupon to be marked redeemed")
+	}
+
+	if err := f.contract.RedeemCoupon(f.as(applicationId), assetId, coupon.Id); err == nil || err.Error() != "coupon "+coupon.Id+" already redeemed" {
+		t.Fatalf("expected a second redemption to be rejected, got %v", err)
+	}
+
+	if err := f.contract.RedeemCoupon(f.as(applicationId), assetId, "missing"); err == nil || err.Error() != "no coupon found for missing" {
+		t.Fatalf("expected an unknown coupon to be rejected, got %v", err)
+	}
+}
+
+func TestExpiredCouponCannotBeRedeemed(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.init(assetRequest())
+
+	if err := f.contract.ConfigureCoupon(f.as(applicationId), assetId, CouponTermsRequest{Value: 1000}); err != nil {
+		t.Fatalf("ConfigureCoupon: %s", err)
+	}
+
+	for _, id := range []string{applicationId, processId} {
+		f.contract.Sign(f.as(id), assetId, "")
+	}
+
+	f.contract.RecordDelivery(f.as(processId), assetId, "d1", "2025-01-01T00:00:00Z")
+
+	coupons, _ := f.contract.GetCoupons(f.as(applicationId), assetId)
+
+	if len(coupons) != 1 || coupons[0].Currency != "USD" || !coupons[0].ExpiresAt.Equal(f.now.Add(30*24*time.Hour)) {
+		t.Fatalf("expected a USD coupon valid for the default 30 days, got %+v", coupons)
+	}
+
+	f.advance(30*24*time.Hour + time.Second)
+
+	err := f.contract.RedeemCoupon(f.as(applicationId), assetId, coupons[0].Id)
+
+	if err == nil || err.Error() != "coupon "+coupons[0].Id+" expired at 2024-07-01T12:00:00Z" {
+		t.Fatalf("expected the expired coupon to be rejected, got %v", err)
+	}
+}
+
+func TestCouponRequiresAConfiguredValue(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.init(assetRequest())
+
+	if err := f.contract.ConfigureCoupon(f.as(applicationId), assetId, CouponTermsRequest{DeliveryDeadline: "2024-06-10T00:00:00Z"}); err != nil {
+		t.Fatalf("ConfigureCoupon: %s", err)
+	}
+
+	for _, id := range []string{applicationId, processId} {
+		f.contract.Sign(f.as(id), assetId, "")
+	}
+
+	if _, err := f.contract.RecordDelivery(f.as(processId), assetId, "d1", "2024-06-11T00:00:00Z"); err == nil || err.Error() != "coupon value is not configured" {
+		t.Fatalf("expected a late delivery without a coupon value to be rejected, got %v", err)
+	}
+
+	if coupons, _ := f.contract.GetCoupons(f.as(applicationId), assetId); len(coupons) != 0 {
+		t.Fatalf("expected no coupon, got %+v", coupons)
+	}
+}
+
+func TestConfigureCouponKeepsTheValueWhenOmitted(t *testing.T) {
+	f := newFixture(t)
+
+	assetId := f.init(assetRequest())
+
+	if err := f.contract.ConfigureCoupon(f.as(applicationId), assetId, CouponTermsRequest{Value: 1500}); err != nil {
+		t.Fatalf("ConfigureCoupon: %s", err)
+	}
+
+	if err := f.contract.ConfigureCoupon(f.as(applicationId), assetId, CouponTermsRequest{Currency: "BRL"}); err != nil {
+		t.Fatalf("ConfigureCoupon: %s", err)
+	}
+
+	asset, _ := f.contract.QueryAsset(f.as(applicationId), assetId)
+
+	terms, err := f.contract.couponTerms(f.as(applicationId), asset)
+
+	if err != nil || terms.CouponValue != 1500 || terms.CouponCurrency != "BRL" {
+		t.Fatalf("expected the value to survive a later update, got %+v, %v", terms, err)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+)
+
+const (
+	applicationId = "application-id"
+	processId     = "process-id"
+	outsiderId    = "outsider-id"
+)
+
+type mockIdentity struct {
+	id string
+}
+
+func (m *mockIdentity) GetID() (string, error) {
+	return m.id, nil
+}
+
+func (m *mockIdentity) GetMSPID() (string, error) {
+	return "Org1MSP", nil
+}
+
+func (m *mockIdentity) GetAttributeValue(attrName string) (string, bool, error) {
+	return "", false, nil
+}
+
+func (m *mockIdentity) AssertAttributeValue(attrName string, attrValue string) error {
+	return fmt.Errorf("attribute %s not found", attrName)
+}
+
+func (m *mockIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return nil, nil
+}
+
+type fixture struct {
+	t        *testing.T
+	contract *SmartContract
+	stub     *shimtest.MockStub
+	now      time.Time
+	txs      int
+}
+
+func newFixture(t *testing.T) *fixture {
+	f := &fixture{
+		t:        t,
+		contract: new(SmartContract),
+		stub:     shimtest.NewMockStub("discount-coupon-late-delivery", nil),
+		now:      time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	previous := nowFunc
+	nowFunc = func() time.Time { return f.now }
+
+	t.Cleanup(func() { nowFunc = previous })
+
+	return f
+}
+
+// as starts a new transaction submitted by id.
+func (f *fixture) as(id string) *TransactionContext {
+	f.txs++
+	f.stub.MockTransactionStart(fmt.Sprintf("tx%d", f.txs))
+
+	ctx := new(TransactionContext)
+	ctx.SetStub(f.stub)
+	ctx.SetClientIdentity(&mockIdentity{id: id})
+
+	return ctx
+}
+
+func (f *fixture) advance(d time.Duration) {
+	f.now = f.now.Add(d)
+}
+
+func assetRequest() AssetRequest {
+	return AssetRequest{
+		BeginDate: "2024-01-01T00:00:00Z",
+		DueDate:   "2024-12-31T00:00:00Z",
+		Parties: PartiesRequest{
+			Application: PartyRequest{Name: "Store", Id: applicationId},
+			Process:     PartyRequest{Name: "Carrier", Id: processId},
+		},
+	}
+}
+
+func (f *fixture) init(request AssetRequest) string {
+	f.t.Helper()
+
+	assetId, err := f.contract.Init(f.as(applicationId), request)
+
+	if err != nil {
+		f.t.Fatalf("Init: %s", err)
+	}
+
+	return assetId
+}
+
+// signed creates an asset from request and signs it by both parties.
+func (f *fixture) signed(request AssetRequest) string {
+	f.t.Helper()
+
+	assetId := f.init(request)
+
+	for _, id := range []string{applicationId, processId} {
+		if err := f.contract.Sign(f.as(id), assetId, ""); err != nil {
+			f.t.Fatalf("Sign as %s: %s", id, err)
+		}
+	}
+
+	return assetId
+}
@@ -6,7 +6,7 @@ require github.com/hyperledger/fabric-contract-api-go v1.2.1
 
 require github.com/google/uuid v1.3.1
 
-require github.com/hyperledger/fabric-chaincode-go v0.0.0-20230731094759-d626e9ab09b9 // indirect
+require github.com/hyperledger/fabric-chaincode-go v0.0.0-20230731094759-d626e9ab09b9
 
 require (
 	github.com/go-openapi/jsonpointer v0.19.5 // indirect
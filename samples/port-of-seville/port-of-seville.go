@@ -12,100 +12,628 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/google/uuid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
+// MONTH is a fixed 30-day month; calendar months are not tracked.
+var timeInSeconds = map[string]int{
+	"SECOND": 1,
+	"MINUTE": 1 * 60,
+	"HOUR":   1 * 60 * 60,
+	"DAY":    1 * 60 * 60 * 24,
+	"WEEK":   1 * 60 * 60 * 24 * 7,
+	"MONTH":  1 * 60 * 60 * 24 * 30,
+}
+
+const (
+	RequestPending     = "PENDING"
+	RequestApproved    = "APPROVED"
+	RequestFulfilled   = "FULFILLED"
+	RequestCompensated = "COMPENSATED"
+)
+
+var requestTransitions = map[string][]string{
+	RequestPending:   {RequestApproved},
+	RequestApproved:  {RequestFulfilled},
+	RequestFulfilled: {RequestCompensated},
+}
+
+var enforceRequestLifecycle = true
+
+var ErrAssetNotFound = errors.New("asset not found")
+
+var ErrCorruptAsset = errors.New("corrupt asset")
+
+var ErrAssetExpired = errors.New("asset expired")
+
+const currentSchemaVersion = 4
+
+const contractExpiredEvent = "ContractExpired"
+
+const contractExpiredRule = "contractExpired"
+
+const requestObjectType = "request"
+
+const contactObjectType = "contact"
+
+const usageObjectType = "usage"
+
+const eventObjectType = "event"
+
+const (
+	ContractDraft             = "DRAFT"
+	ContractPendingSignatures = "PENDING_SIGNATURES"
+	ContractActive            = "ACTIVE"
+	ContractExpired           = "EXPIRED"
+	ContractSuspended         = "SUSPENDED"
+	ContractCancelled         = "CANCELLED"
+	ContractTerminated        = "TERMINATED"
+)
+
+const (
+	SignResultSigned        = "SIGNED"
+	SignResultAlreadySigned = "ALREADY_SIGNED"
+	SignResultNotAParty     = "NOT_A_PARTY"
+	SignResultError         = "ERROR"
+)
+
+const (
+	QuorumAll      = "ALL"
+	QuorumMajority = "MAJORITY"
+)
+
+const extendDueDateOperation = "ExtendDueDate"
+
+const replacePartyIdentityOperation = "ReplacePartyIdentity"
+
+const terminateOperation = "Terminate"
+
+var invalidatesSignatures = map[string]bool{
+	extendDueDateOperation: true,
+}
+
+const (
+	RoleApplication = "application"
+	RoleProcess     = "process"
+)
+
+var renewalWindow = 30 * 24 * time.Hour
+
+var strictBeginDate = false
+
+var rangePageSize int32 = 100
+
+var useRichQueries = false
+
+var beginDateTolerance = 24 * time.Hour
+
+var rightRequestBerthingRules = map[string]bool{
+	"messageContent02": true,
+	"messageContent12": true,
+	"messageContent22": true,
+	"messageContent32": true,
+}
+
+var obligationRespondToPortProposalRules = map[string]bool{
+	"timeout":          true,
+	"messageContent12": true,
+	"messageContent22": true,
+}
+
+var prohibitionNotAllowedRequestBerthingRules = map[string]bool{}
+
+var obligationRespondToBerthingRequestRules = map[string]bool{
+	"timeout": true,
+}
+
+var deterministicAssetIds = false
+
+var restrictReads = false
+
+var isoDurationPattern = regexp.MustCompile(`^P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+type Logger interface {
+	Infof(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+type noopLogger struct{}
+
+func (noopLogger) Infof(format string, args ...interface{}) {}
+
+func (noopLogger) Errorf(format string, args ...interface{}) {}
+
+var logger Logger = noopLogger{}
+
+var nowFunc = time.Now
+
+var contractVersion = "dev"
+
+var engineVersion = "0.0.8"
+
+type VersionInfo struct {
+	ContractVersion string `json:"contractVersion"`
+	EngineVersion   string `json:"engineVersion"`
+}
+
 type SmartContract struct {
 	contractapi.Contract
 }
 
+type TransactionContext struct {
+	contractapi.TransactionContext
+
+	states map[string][]byte
+
+	eventSet bool
+}
+
 type Party struct {
-	Id            string
-	Name          string
-	IsSigned      bool
-	SignatureDate time.Time
+	Id            string    `json:"id"`
+	Name          string    `json:"name"`
+	IsSigned      bool      `json:"isSigned"`
+	SignatureDate time.Time `json:"signatureDate"`
+	SignatureRef  string    `json:"signatureRef"`
+}
+
+type SignatureEntry struct {
+	ClientId string    `json:"clientId"`
+	Role     string    `json:"role"`
+	SignedAt time.Time `json:"signedAt"`
+}
+
+type ContractEvent struct {
+	Seq       int       `json:"seq"`
+	Name      string    `json:"name"`
+	Payload   string    `json:"payload"`
+	TxId      string    `json:"txId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ContractExpiredPayload struct {
+	AssetId string    `json:"assetId"`
+	DueDate time.Time `json:"dueDate"`
+}
+
+type RemainingOperations struct {
+	Remaining     int  `json:"remaining"`
+	WindowExpired bool `json:"windowExpired"`
+}
+
+type ContractDuration struct {
+	Seconds   int64          `json:"seconds"`
+	Breakdown map[string]int `json:"breakdown"`
+	Text      string         `json:"text"`
+}
+
+type PendingApproval struct {
+	Operation  string    `json:"operation"`
+	Value      string    `json:"value"`
+	ApprovedBy []string  `json:"approvedBy"`
+	AwaitingBy []string  `json:"awaitingBy"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type ContractSummary struct {
+	Id                  string                         `json:"id"`
+	ApplicationName     string                         `json:"applicationName"`
+	ProcessName         string                         `json:"processName"`
+	Status              string                         `json:"status"`
+	SignedCount         int                            `json:"signedCount"`
+	RequestCount        int                            `json:"requestCount"`
+	RemainingOperations map[string]RemainingOperations `json:"remainingOperations"`
+}
+
+type AuditEntry struct {
+	Type        string    `json:"type"`
+	Timestamp   time.Time `json:"timestamp"`
+	TxId        string    `json:"txId,omitempty"`
+	ClientId    string    `json:"clientId,omitempty"`
+	Description string    `json:"description"`
+}
+
+type PartyContact struct {
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+}
+
+type SignatureStatus struct {
+	Role          string    `json:"role"`
+	Id            string    `json:"id"`
+	IsSigned      bool      `json:"isSigned"`
+	SignatureDate time.Time `json:"signatureDate"`
+	SignatureRef  string    `json:"signatureRef,omitempty"`
+}
+
+type SignatureStatusResult struct {
+	Signatures []SignatureStatus `json:"signatures,omitempty" metadata:",optional"`
+	Error      string            `json:"error,omitempty" metadata:",optional"`
+}
+
+type InitResult struct {
+	Id    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type SignResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
 }
 
 type Parties struct {
-	Application Party
-	Process     Party
+	Application Party `json:"application"`
+	Process     Party `json:"process"`
 }
 
 type Interval struct {
-	Start time.Time `json:"start"`
-	End   time.Time `json:"end"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Timezone string    `json:"timezone,omitempty" metadata:",optional"`
 }
 
+type BusinessHoursConfig struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Timezone string `json:"timezone,omitempty" metadata:",optional"`
+}
+
+// Timeout holds the seconds a response has after the request it answers was created.
 type Timeout struct {
-	Increase int       `json:"increase"`
-	End      time.Time `json:"end"`
+	Increase int `json:"increase"`
 }
 
 type MaxNumberOfOperation struct {
-	Max      int       `json:"max"`
-	Used     int       `json:"used"`
-	Start    time.Time `json:"start"`
-	End      time.Time `json:"end"`
-	TimeUnit string    `json:"timeUnit"`
+	Max      int    `json:"max"`
+	TimeUnit string `json:"timeUnit"`
+}
+
+// ClauseUsage holds the per-call counters of a clause. It lives under its own key so a
+// clause call never rewrites the asset document; calls on the same clause still share it.
+type ClauseUsage struct {
+	Used          int                  `json:"used"`
+	Start         time.Time            `json:"start"`
+	End           time.Time            `json:"end"`
+	LastRequestAt map[string]time.Time `json:"lastRequestAt"`
 }
 
 type RightRequestBerthing struct {
+	MinIntervalSeconds int `json:"minIntervalSeconds"`
+
+	Window Interval `json:"window"`
+
+	BusinessHours Interval `json:"businessHours"`
+
+	AllowedMSPs []string `json:"allowedMSPs"`
+
+	RuleWeights    map[string]int `json:"ruleWeights"`
+	ScoreThreshold float64        `json:"scoreThreshold"`
+
+	CancellationWindowSeconds int `json:"cancellationWindowSeconds"`
+}
+
+type RightRequestBerthingConfig struct {
+	MinIntervalSeconds int `json:"minIntervalSeconds,omitempty" metadata:",optional"`
+
+	// Window is an ISO-8601 duration (e.g. P1M, P30D, PT2H) counted from the begin date.
+	Window string `json:"window,omitempty" metadata:",optional"`
+
+	BusinessHours *BusinessHoursConfig `json:"businessHours,omitempty" metadata:",optional"`
+
+	AllowedMSPs []string `json:"allowedMSPs,omitempty" metadata:",optional"`
+
+	RuleWeights    map[string]int `json:"ruleWeights,omitempty" metadata:",optional"`
+	ScoreThreshold float64        `json:"scoreThreshold,omitempty" metadata:",optional"`
+
+	// CancellationWindowSeconds bounds how long after creation a request may be cancelled.
+	CancellationWindowSeconds int `json:"cancellationWindowSeconds,omitempty" metadata:",optional"`
 }
 
 type RightRequestBerthingArgs struct {
 	MessageContent02 string `json:"messageContent02"`
-
 	MessageContent12 string `json:"messageContent12"`
-
 	MessageContent22 string `json:"messageContent22"`
-
 	MessageContent32 string `json:"messageContent32"`
+
+	ClientRequestId string `json:"clientRequestId,omitempty" metadata:",optional"`
+}
+
+type RightRequestBerthingLimits struct {
+	MinIntervalSeconds        int `json:"minIntervalSeconds"`
+	CancellationWindowSeconds int `json:"cancellationWindowSeconds"`
+
+	AllowedMSPs []string `json:"allowedMSPs,omitempty"`
 }
 
 type ObligationRespondToPortProposal struct {
 	ObligationRespondToPortProposalTimeout0 Timeout `json:"obligationRespondToPortProposalTimeout0"`
+
+	MinIntervalSeconds int `json:"minIntervalSeconds"`
+
+	Window Interval `json:"window"`
+
+	BusinessHours Interval `json:"businessHours"`
+
+	AllowedMSPs []string `json:"allowedMSPs"`
+
+	RuleWeights    map[string]int `json:"ruleWeights"`
+	ScoreThreshold float64        `json:"scoreThreshold"`
+
+	DiscountPercentage int `json:"discountPercentage"`
+}
+
+type ObligationRespondToPortProposalConfig struct {
+	MinIntervalSeconds int `json:"minIntervalSeconds,omitempty" metadata:",optional"`
+
+	// Window is an ISO-8601 duration (e.g. P1M, P30D, PT2H) counted from the begin date.
+	Window string `json:"window,omitempty" metadata:",optional"`
+
+	BusinessHours *BusinessHoursConfig `json:"businessHours,omitempty" metadata:",optional"`
+
+	AllowedMSPs []string `json:"allowedMSPs,omitempty" metadata:",optional"`
+
+	RuleWeights    map[string]int `json:"ruleWeights,omitempty" metadata:",optional"`
+	ScoreThreshold float64        `json:"scoreThreshold,omitempty" metadata:",optional"`
+
+	// DiscountPercentage is recorded as a penalty each time a call meets the clause terms.
+	DiscountPercentage int `json:"discountPercentage,omitempty" metadata:",optional"`
 }
 
 type ObligationRespondToPortProposalArgs struct {
 	MessageContent12 string `json:"messageContent12"`
-
 	MessageContent22 string `json:"messageContent22"`
+
+	RequestId string `json:"requestId"`
+}
+
+type ObligationRespondToPortProposalLimits struct {
+	MinIntervalSeconds int `json:"minIntervalSeconds"`
+
+	AllowedMSPs []string `json:"allowedMSPs,omitempty"`
 }
 
 type ProhibitionNotAllowedRequestBerthing struct {
+	MinIntervalSeconds int `json:"minIntervalSeconds"`
+
+	Window Interval `json:"window"`
+
+	BusinessHours Interval `json:"businessHours"`
+
+	AllowedMSPs []string `json:"allowedMSPs"`
+
+	RuleWeights    map[string]int `json:"ruleWeights"`
+	ScoreThreshold float64        `json:"scoreThreshold"`
+
+	CancellationWindowSeconds int `json:"cancellationWindowSeconds"`
+}
+
+type ProhibitionNotAllowedRequestBerthingConfig struct {
+	MinIntervalSeconds int `json:"minIntervalSeconds,omitempty" metadata:",optional"`
+
+	// Window is an ISO-8601 duration (e.g. P1M, P30D, PT2H) counted from the begin date.
+	Window string `json:"window,omitempty" metadata:",optional"`
+
+	BusinessHours *BusinessHoursConfig `json:"businessHours,omitempty" metadata:",optional"`
+
+	AllowedMSPs []string `json:"allowedMSPs,omitempty" metadata:",optional"`
+
+	RuleWeights    map[string]int `json:"ruleWeights,omitempty" metadata:",optional"`
+	ScoreThreshold float64        `json:"scoreThreshold,omitempty" metadata:",optional"`
+
+	// CancellationWindowSeconds bounds how long after creation a request may be cancelled.
+	CancellationWindowSeconds int `json:"cancellationWindowSeconds,omitempty" metadata:",optional"`
+}
+
+type ProhibitionNotAllowedRequestBerthingArgs struct {
+	ClientRequestId string `json:"clientRequestId,omitempty" metadata:",optional"`
+}
+
+type ProhibitionNotAllowedRequestBerthingLimits struct {
+	MinIntervalSeconds        int `json:"minIntervalSeconds"`
+	CancellationWindowSeconds int `json:"cancellationWindowSeconds"`
+
+	AllowedMSPs []string `json:"allowedMSPs,omitempty"`
 }
 
 type ObligationRespondToBerthingRequest struct {
 	ObligationRespondToBerthingRequestTimeout0 Timeout `json:"obligationRespondToBerthingRequestTimeout0"`
+
+	MinIntervalSeconds int `json:"minIntervalSeconds"`
+
+	Window Interval `json:"window"`
+
+	BusinessHours Interval `json:"businessHours"`
+
+	AllowedMSPs []string `json:"allowedMSPs"`
+
+	RuleWeights    map[string]int `json:"ruleWeights"`
+	ScoreThreshold float64        `json:"scoreThreshold"`
+
+	DiscountPercentage int `json:"discountPercentage"`
+}
+
+type ObligationRespondToBerthingRequestConfig struct {
+	MinIntervalSeconds int `json:"minIntervalSeconds,omitempty" metadata:",optional"`
+
+	// Window is an ISO-8601 duration (e.g. P1M, P30D, PT2H) counted from the begin date.
+	Window string `json:"window,omitempty" metadata:",optional"`
+
+	BusinessHours *BusinessHoursConfig `json:"businessHours,omitempty" metadata:",optional"`
+
+	AllowedMSPs []string `json:"allowedMSPs,omitempty" metadata:",optional"`
+
+	RuleWeights    map[string]int `json:"ruleWeights,omitempty" metadata:",optional"`
+	ScoreThreshold float64        `json:"scoreThreshold,omitempty" metadata:",optional"`
+
+	// DiscountPercentage is recorded as a penalty each time a call meets the clause terms.
+	DiscountPercentage int `json:"discountPercentage,omitempty" metadata:",optional"`
+}
+
+type ObligationRespondToBerthingRequestArgs struct {
+	RequestId string `json:"requestId"`
+}
+
+type ObligationRespondToBerthingRequestLimits struct {
+	MinIntervalSeconds int `json:"minIntervalSeconds"`
+
+	AllowedMSPs []string `json:"allowedMSPs,omitempty"`
+}
+
+type RequestCancellationArgs struct {
+	RequestId string `json:"requestId"`
+}
+
+type Obligation struct {
+	Id          string    `json:"id"`
+	Party       string    `json:"party"`
+	Description string    `json:"description"`
+	Deadline    time.Time `json:"deadline"`
+	Fulfilled   bool      `json:"fulfilled"`
+	FulfilledAt time.Time `json:"fulfilledAt"`
+	Overdue     bool      `json:"overdue"`
+}
+
+type Prohibition struct {
+	Party     string `json:"party"`
+	Operation string `json:"operation"`
+}
+
+type Amendment struct {
+	Operation string    `json:"operation"`
+	Value     string    `json:"value"`
+	Approvals []string  `json:"approvals"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	InvalidatesSignatures bool `json:"invalidatesSignatures"`
 }
 
+type DateChange struct {
+	Old       time.Time `json:"old"`
+	New       time.Time `json:"new"`
+	ChangedBy string    `json:"changedBy"`
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+// Penalty records a discount owed to the counterparty because a call met the terms of an
+// obligation clause. The discount is kept in basis points, hundredths of a percent, so a
+// penalty below one percent is not lost to rounding.
+type Penalty struct {
+	Clause              string    `json:"clause"`
+	DiscountBasisPoints int       `json:"discountBasisPoints"`
+	CreatedAt           time.Time `json:"createdAt"`
+}
+
+// Request records one call of a request clause along with the arguments it was called with.
 type Request struct {
-	clientId  string
-	createdAt time.Time
+	Id            string          `json:"id"`
+	Clause        string          `json:"clause"`
+	ClientId      string          `json:"clientId"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	State         string          `json:"state"`
+	Valid         bool            `json:"valid"`
+	FailedRules   []string        `json:"failedRules"`
+	FailureReason string          `json:"failureReason,omitempty"`
+	Score         float64         `json:"score"`
+	Args          json.RawMessage `json:"args,omitempty" metadata:",optional"`
+
+	Cancelled   bool      `json:"cancelled"`
+	CancelledAt time.Time `json:"cancelledAt,omitempty" metadata:",optional"`
+}
+
+type ValidationResult struct {
+	Valid       bool     `json:"valid"`
+	FailedRules []string `json:"failedRules"`
+	RequestId   string   `json:"requestId"`
+	Score       float64  `json:"score"`
+
+	InGracePeriod bool `json:"inGracePeriod"`
 }
 
 type Asset struct {
-	Parties   Parties
-	BeginDate time.Time
-	DueDate   time.Time
-	IsSigned  bool
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Requests  map[string]Request
+	SchemaVersion int `json:"schemaVersion"`
+
+	Id        string    `json:"id"`
+	Parties   Parties   `json:"parties"`
+	BeginDate time.Time `json:"beginDate"`
+	DueDate   time.Time `json:"dueDate"`
+	IsSigned  bool      `json:"isSigned"`
+	CreatedAt time.Time `json:"createdAt"`
+	CreatedBy string    `json:"createdBy"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// Deprecated: requests are stored under their own keys. Requests only holds those written
+	// by older chaincode versions; read them through listRequests, which merges both.
+	Requests map[string]Request `json:"requests"`
+
+	Obligations map[string]Obligation `json:"obligations"`
+
+	PenaltiesApplied []Penalty `json:"penaltiesApplied"`
+
+	Prohibitions []Prohibition `json:"prohibitions"`
+
+	DependsOn    map[string][]string  `json:"dependsOn"`
+	FiredClauses map[string]time.Time `json:"firedClauses"`
+
+	Quorum            string               `json:"quorum"`
+	PendingAmendments map[string]Amendment `json:"pendingAmendments"`
+
+	PreviousAssetId string `json:"previousAssetId"`
+
+	DueDateHistory []DateChange `json:"dueDateHistory"`
 
-	RightRequestBerthing RightRequestBerthing
+	SignatureLog []SignatureEntry `json:"signatureLog"`
 
-	ObligationRespondToPortProposal ObligationRespondToPortProposal
+	GracePeriodSeconds int `json:"gracePeriodSeconds"`
 
-	ProhibitionNotAllowedRequestBerthing ProhibitionNotAllowedRequestBerthing
+	ExpiredNotified bool `json:"expiredNotified"`
 
-	ObligationRespondToBerthingRequest ObligationRespondToBerthingRequest
+	EventSeq int `json:"eventSeq"`
+
+	Tags map[string]string `json:"tags"`
+
+	// DocumentHash and DocumentURI bind the contract to its off-chain legal document.
+	DocumentHash string `json:"documentHash"`
+	DocumentURI  string `json:"documentUri"`
+
+	Deleted   bool      `json:"deleted"`
+	DeletedBy string    `json:"deletedBy"`
+	DeletedAt time.Time `json:"deletedAt"`
+
+	Cancelled       bool      `json:"cancelled"`
+	CancelledReason string    `json:"cancelledReason"`
+	CancelledAt     time.Time `json:"cancelledAt"`
+
+	Terminated       bool      `json:"terminated"`
+	TerminatedReason string    `json:"terminatedReason"`
+	TerminatedAt     time.Time `json:"terminatedAt"`
+
+	Suspended   bool      `json:"suspended"`
+	SuspendedAt time.Time `json:"suspendedAt"`
+	ResumedAt   time.Time `json:"resumedAt"`
+
+	RightRequestBerthing RightRequestBerthing `json:"rightRequestBerthing"`
+
+	ObligationRespondToPortProposal ObligationRespondToPortProposal `json:"obligationRespondToPortProposal"`
+
+	ProhibitionNotAllowedRequestBerthing ProhibitionNotAllowedRequestBerthing `json:"prohibitionNotAllowedRequestBerthing"`
+
+	ObligationRespondToBerthingRequest ObligationRespondToBerthingRequest `json:"obligationRespondToBerthingRequest"`
 }
 
 type PartyRequest struct {
@@ -118,10 +646,90 @@ type PartiesRequest struct {
 	Process     PartyRequest `json:"process"`
 }
 
+type ObligationRequest struct {
+	Party       string `json:"party"`
+	Description string `json:"description"`
+	Deadline    string `json:"deadline"`
+}
+
 type AssetRequest struct {
+	Id        string         `json:"id,omitempty" metadata:",optional"`
 	BeginDate string         `json:"beginDate"`
 	DueDate   string         `json:"dueDate"`
 	Parties   PartiesRequest `json:"parties"`
+
+	GracePeriodSeconds int `json:"gracePeriodSeconds,omitempty" metadata:",optional"`
+
+	Obligations []ObligationRequest `json:"obligations,omitempty" metadata:",optional"`
+
+	Prohibitions []Prohibition `json:"prohibitions,omitempty" metadata:",optional"`
+
+	// DependsOn maps a clause to the clauses that must have fired before it can run.
+	DependsOn map[string][]string `json:"dependsOn,omitempty" metadata:",optional"`
+
+	RightRequestBerthing RightRequestBerthingConfig `json:"rightRequestBerthing,omitempty" metadata:",optional"`
+
+	ObligationRespondToPortProposal ObligationRespondToPortProposalConfig `json:"obligationRespondToPortProposal,omitempty" metadata:",optional"`
+
+	ProhibitionNotAllowedRequestBerthing ProhibitionNotAllowedRequestBerthingConfig `json:"prohibitionNotAllowedRequestBerthing,omitempty" metadata:",optional"`
+
+	ObligationRespondToBerthingRequest ObligationRespondToBerthingRequestConfig `json:"obligationRespondToBerthingRequest,omitempty" metadata:",optional"`
+
+	Quorum string `json:"quorum,omitempty" metadata:",optional"`
+
+	Tags map[string]string `json:"tags,omitempty" metadata:",optional"`
+
+	DocumentHash string `json:"documentHash,omitempty" metadata:",optional"`
+	DocumentURI  string `json:"documentUri,omitempty" metadata:",optional"`
+}
+
+type ClauseArgument struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type ClauseDescriptor struct {
+	Name      string           `json:"name"`
+	Arguments []ClauseArgument `json:"arguments"`
+}
+
+var clauseDescriptors = []ClauseDescriptor{
+	{
+		Name: "RightRequestBerthing",
+		Arguments: []ClauseArgument{
+			{Name: "messageContent02", Type: "string"},
+			{Name: "messageContent12", Type: "string"},
+			{Name: "messageContent22", Type: "string"},
+			{Name: "messageContent32", Type: "string"},
+			{Name: "clientRequestId", Type: "string"},
+		},
+	},
+	{
+		Name: "ObligationRespondToPortProposal",
+		Arguments: []ClauseArgument{
+			{Name: "messageContent12", Type: "string"},
+			{Name: "messageContent22", Type: "string"},
+			{Name: "requestId", Type: "string"},
+		},
+	},
+	{
+		Name: "ProhibitionNotAllowedRequestBerthing",
+		Arguments: []ClauseArgument{
+			{Name: "clientRequestId", Type: "string"},
+		},
+	},
+	{
+		Name: "ObligationRespondToBerthingRequest",
+		Arguments: []ClauseArgument{
+			{Name: "requestId", Type: "string"},
+		},
+	},
+	{
+		Name: "RequestCancellation",
+		Arguments: []ClauseArgument{
+			{Name: "requestId", Type: "string"},
+		},
+	},
 }
 
 func (s *SmartContract) isParty(id string, asset *Asset) (bool, error) {
@@ -134,6 +742,17 @@ func (s *SmartContract) isParty(id string, asset *Asset) (bool, error) {
 	return value, nil
 }
 
+func (s *SmartContract) partyByRole(asset *Asset, role string) (*Party, error) {
+	switch role {
+	case RoleApplication:
+		return &asset.Parties.Application, nil
+	case RoleProcess:
+		return &asset.Parties.Process, nil
+	}
+
+	return nil, fmt.Errorf("unknown role: %s, expected one of %s/%s", role, RoleApplication, RoleProcess)
+}
+
 func (s *SmartContract) isSigned(party Party) (bool, error) {
 	if party.IsSigned {
 		return party.IsSigned, fmt.Errorf("the asset is already signed")
@@ -150,143 +769,3797 @@ func (s *SmartContract) assetIsSigned(asset *Asset) error {
 	return fmt.Errorf("asset is not signed")
 }
 
-func (s *SmartContract) isBetweenBeginDateAndDueDate(asset *Asset) error {
-	if asset.DueDate.Before(time.Now()) {
-		return fmt.Errorf("asset expired. The current date is after the due date")
+func (s *SmartContract) gracePeriodEnd(asset *Asset) time.Time {
+	return asset.DueDate.Add(time.Duration(asset.GracePeriodSeconds) * time.Second)
+}
+
+func (s *SmartContract) isInGracePeriod(asset *Asset) bool {
+	now := nowFunc().UTC()
+
+	return now.After(asset.DueDate) && !now.After(s.gracePeriodEnd(asset))
+}
+
+// isBetweenBeginDateAndDueDate accepts the inclusive window [BeginDate, DueDate + GracePeriodSeconds],
+// so an operation at exactly the begin or due instant is allowed.
+func (s *SmartContract) isBetweenBeginDateAndDueDate(asset *Asset) error {
+	now := nowFunc().UTC()
+
+	if s.gracePeriodEnd(asset).Before(now) {
+		return fmt.Errorf("%w. The current date is after the due date", ErrAssetExpired)
+	}
+
+	if asset.BeginDate.After(now) {
+		return fmt.Errorf("the current date is before the start date")
+	}
+
+	return nil
+}
+
+// canSign allows signing at any time up to the due date, including before the begin date,
+// while clause execution still requires the active window.
+func (s *SmartContract) canSign(asset *Asset) error {
+	if err := s.isTerminated(asset); err != nil {
+		return err
+	}
+
+	if err := s.isCancelled(asset); err != nil {
+		return err
+	}
+
+	if asset.DueDate.Before(nowFunc().UTC()) {
+		return fmt.Errorf("asset expired. The current date is after the due date")
+	}
+
+	return nil
+}
+
+func (s *SmartContract) checkProhibition(asset *Asset, clientId string, operation string) error {
+	for _, prohibition := range asset.Prohibitions {
+		if prohibition.Operation != operation {
+			continue
+		}
+
+		party, err := s.partyByRole(asset, prohibition.Party)
+
+		if err != nil {
+			return err
+		}
+
+		if party.Id == clientId {
+			return fmt.Errorf("operation prohibited for this party")
+		}
+	}
+
+	return nil
+}
+
+func (s *SmartContract) isTerminated(asset *Asset) error {
+	if asset.Terminated {
+		return fmt.Errorf("contract terminated")
+	}
+
+	return nil
+}
+
+func (s *SmartContract) isCancelled(asset *Asset) error {
+	if asset.Cancelled {
+		return fmt.Errorf("contract cancelled")
+	}
+
+	return nil
+}
+
+func (s *SmartContract) resetSignatures(asset *Asset) {
+	for _, party := range []*Party{&asset.Parties.Application, &asset.Parties.Process} {
+		party.IsSigned = false
+		party.SignatureDate = time.Time{}
+		party.SignatureRef = ""
+	}
+
+	asset.IsSigned = false
+}
+
+// isQuorumReached counts the parties on the asset. With the two parties a contract has
+// today a majority is both of them, so MAJORITY only differs from ALL once more parties
+// can join.
+func (s *SmartContract) isQuorumReached(asset *Asset, approvals int) bool {
+	parties := 0
+
+	for _, party := range []*Party{&asset.Parties.Application, &asset.Parties.Process} {
+		if party.Id != "" {
+			parties++
+		}
+	}
+
+	if asset.Quorum == QuorumMajority {
+		return approvals*2 > parties
+	}
+
+	return approvals >= parties
+}
+
+func (s *SmartContract) recordApproval(asset *Asset, op string, clientId string) (bool, error) {
+	amendment, exists := asset.PendingAmendments[op]
+
+	if !exists {
+		return false, fmt.Errorf("no pending amendment found for %s", op)
+	}
+
+	for _, approval := range amendment.Approvals {
+		if approval == clientId {
+			return false, fmt.Errorf("amendment %s already approved by this party", op)
+		}
+	}
+
+	amendment.Approvals = append(amendment.Approvals, clientId)
+	asset.PendingAmendments[op] = amendment
+
+	return s.isQuorumReached(asset, len(amendment.Approvals)), nil
+}
+
+func (s *SmartContract) isSuspended(asset *Asset) error {
+	if asset.Suspended {
+		return fmt.Errorf("contract suspended")
+	}
+
+	return nil
+}
+
+func (s *SmartContract) canExecuteClause(asset *Asset) error {
+	if err := s.isTerminated(asset); err != nil {
+		return err
+	}
+
+	if err := s.isBetweenBeginDateAndDueDate(asset); err != nil {
+		return err
+	}
+
+	if err := s.isSuspended(asset); err != nil {
+		return err
+	}
+
+	return s.assetIsSigned(asset)
+}
+
+func (s *SmartContract) isClauseKnown(clauseName string) bool {
+	for _, descriptor := range clauseDescriptors {
+		if descriptor.Name == clauseName {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *SmartContract) isDependsOnValid(dependsOn map[string][]string) error {
+	for clauseName, prerequisites := range dependsOn {
+		if !s.isClauseKnown(clauseName) {
+			return fmt.Errorf("unknown clause: %s", clauseName)
+		}
+
+		for _, prerequisite := range prerequisites {
+			if !s.isClauseKnown(prerequisite) {
+				return fmt.Errorf("unknown clause: %s", prerequisite)
+			}
+
+			if prerequisite == clauseName {
+				return fmt.Errorf("clause %s cannot depend on itself", clauseName)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *SmartContract) clauseHasFired(asset *Asset, clauseName string) bool {
+	_, fired := asset.FiredClauses[clauseName]
+
+	return fired
+}
+
+func (s *SmartContract) checkDependencies(asset *Asset, clauseName string) error {
+	for _, prerequisite := range asset.DependsOn[clauseName] {
+		if !s.clauseHasFired(asset, prerequisite) {
+			return fmt.Errorf("prerequisite clause not satisfied: %s", prerequisite)
+		}
+	}
+
+	return nil
+}
+
+func (s *SmartContract) markClauseFired(asset *Asset, clauseName string) {
+	if asset.FiredClauses == nil {
+		asset.FiredClauses = make(map[string]time.Time)
+	}
+
+	asset.FiredClauses[clauseName] = nowFunc().UTC()
+}
+
+func (s *SmartContract) isApplicationIdValid(id string) error {
+	if id == "" {
+		return fmt.Errorf("application id is required")
+	}
+
+	return nil
+}
+
+func (s *SmartContract) isProcessIdValid(id string) error {
+	if id == "" {
+		return fmt.Errorf("process id is required")
+	}
+
+	return nil
+}
+
+func (s *SmartContract) isBeginDateValid(beginDate time.Time) error {
+	if beginDate.IsZero() {
+		return fmt.Errorf("begin date is required")
+	}
+
+	return nil
+}
+
+func (s *SmartContract) isBeginDateRecent(beginDate time.Time) error {
+	if strictBeginDate && beginDate.Before(nowFunc().UTC().Add(-beginDateTolerance)) {
+		return fmt.Errorf("begin date is in the past")
+	}
+
+	return nil
+}
+
+func (s *SmartContract) isDueDateValid(dueDate time.Time) error {
+	if dueDate.IsZero() {
+		return fmt.Errorf("due date is required")
+	}
+
+	return nil
+}
+
+func (s *SmartContract) isDueDateGreaterThanBeginDate(beginDate time.Time, dueDate time.Time) error {
+	if beginDate.After(dueDate) {
+		return fmt.Errorf("begin date greater than due date")
+	}
+
+	if beginDate.Equal(dueDate) {
+		return fmt.Errorf("due date must be strictly after begin date")
+	}
+
+	return nil
+}
+
+// addDuration adds an ISO-8601 duration to start. Years, months, weeks and days are
+// calendar units, so P1M from January 31st lands on March 3rd as time.AddDate does.
+func (s *SmartContract) addDuration(start time.Time, value string) (time.Time, error) {
+	matches := isoDurationPattern.FindStringSubmatch(value)
+
+	if matches == nil || value == "P" || strings.HasSuffix(value, "T") {
+		return time.Time{}, fmt.Errorf("invalid ISO-8601 duration: %s, expected a form like P1M, P30D or PT2H", value)
+	}
+
+	amounts := make([]int, len(matches)-1)
+
+	for i, match := range matches[1:] {
+		if match == "" {
+			continue
+		}
+
+		amount, err := strconv.Atoi(match)
+
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid ISO-8601 duration: %s", value)
+		}
+
+		amounts[i] = amount
+	}
+
+	end := start.AddDate(amounts[0], amounts[1], amounts[2]*7+amounts[3])
+
+	return end.Add(time.Duration(amounts[4])*time.Hour + time.Duration(amounts[5])*time.Minute + time.Duration(amounts[6])*time.Second), nil
+}
+
+func (s *SmartContract) parseBusinessHours(config BusinessHoursConfig) (Interval, error) {
+	start, err := time.Parse("15:04", config.Start)
+
+	if err != nil {
+		return Interval{}, fmt.Errorf("invalid business hours start: %s, expected HH:MM", config.Start)
+	}
+
+	end, err := time.Parse("15:04", config.End)
+
+	if err != nil {
+		return Interval{}, fmt.Errorf("invalid business hours end: %s, expected HH:MM", config.End)
+	}
+
+	if !end.After(start) {
+		return Interval{}, fmt.Errorf("business hours end must be after start")
+	}
+
+	if _, err := time.LoadLocation(config.Timezone); err != nil {
+		return Interval{}, fmt.Errorf("unknown timezone: %s", config.Timezone)
+	}
+
+	return Interval{Start: start, End: end, Timezone: config.Timezone}, nil
+}
+
+// withinInterval compares the time of day of now, evaluated in the interval's
+// timezone (UTC when empty), against the interval's start and end clock times.
+func (s *SmartContract) withinInterval(interval Interval, now time.Time) (bool, error) {
+	location, err := time.LoadLocation(interval.Timezone)
+
+	if err != nil {
+		return false, fmt.Errorf("unknown timezone: %s", interval.Timezone)
+	}
+
+	local := now.In(location)
+
+	minutes := local.Hour()*60 + local.Minute()
+	start := interval.Start.Hour()*60 + interval.Start.Minute()
+	end := interval.End.Hour()*60 + interval.End.Minute()
+
+	return minutes >= start && minutes <= end, nil
+}
+
+func (s *SmartContract) isWithinBusinessHours(businessHours Interval) error {
+	if businessHours.Start.IsZero() && businessHours.End.IsZero() {
+		return nil
+	}
+
+	within, err := s.withinInterval(businessHours, nowFunc())
+
+	if err != nil {
+		return err
+	}
+
+	if !within {
+		return fmt.Errorf("outside business hours: %s to %s", businessHours.Start.Format("15:04"), businessHours.End.Format("15:04"))
+	}
+
+	return nil
+}
+
+func (s *SmartContract) isWithinClauseWindow(window Interval) error {
+	if window.End.IsZero() {
+		return nil
+	}
+
+	now := nowFunc().UTC()
+
+	if now.Before(window.Start) || now.After(window.End) {
+		return fmt.Errorf("outside the clause window: %s to %s", window.Start.Format(time.RFC3339), window.End.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+func (s *SmartContract) isTimeUnitValid(timeUnit string) error {
+	if _, exists := timeInSeconds[timeUnit]; !exists {
+		return fmt.Errorf("unsupported time unit: %s, expected one of SECOND/MINUTE/HOUR/DAY/WEEK/MONTH", timeUnit)
+	}
+
+	return nil
+}
+
+// withinTolerance accepts a value that differs from the required one by at most tolerance.
+func (s *SmartContract) withinTolerance(value int, required int, tolerance int) bool {
+	difference := value - required
+
+	if difference < 0 {
+		difference = -difference
+	}
+
+	return difference <= tolerance
+}
+
+// score weighs the passed rules against the configured weights, each rule weighing 1
+// when no weights are set. A clause without rules scores 1.
+func (s *SmartContract) score(rules map[string]bool, weights map[string]int, failedRules []string) float64 {
+	failed := make(map[string]bool)
+
+	for _, rule := range failedRules {
+		failed[rule] = true
+	}
+
+	total := 0
+	passed := 0
+
+	for rule := range rules {
+		weight := 1
+
+		if weights != nil {
+			weight = weights[rule]
+		}
+
+		total += weight
+
+		if !failed[rule] {
+			passed += weight
+		}
+	}
+
+	if total == 0 {
+		return 1
+	}
+
+	return float64(passed) / float64(total)
+}
+
+// shiftWindow moves a clause window by the distance between the old and new begin dates,
+// keeping its length.
+func (s *SmartContract) shiftWindow(window Interval, oldBeginDate time.Time, newBeginDate time.Time) Interval {
+	if window.End.IsZero() {
+		return window
+	}
+
+	shift := newBeginDate.Sub(oldBeginDate)
+
+	return Interval{Start: window.Start.Add(shift), End: window.End.Add(shift)}
+}
+
+func (s *SmartContract) canTransitionRequest(from string, to string) error {
+	if !enforceRequestLifecycle {
+		return nil
+	}
+
+	for _, allowed := range requestTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid request state transition: %s -> %s", from, to)
+}
+
+func (s *SmartContract) string2Time(date string) (time.Time, error) {
+	parsed, err := time.Parse(time.RFC3339, date)
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date. Expected format 2006-01-02T15:04:05Z07:00. Recieved: %s", err.Error())
+	}
+
+	return parsed.UTC(), nil
+}
+
+func (s *SmartContract) putState(ctx contractapi.TransactionContextInterface, assetId string, asset *Asset) error {
+	contractAsBytes, err := json.Marshal(asset)
+
+	if err != nil {
+		return fmt.Errorf("marshal error: %s", err.Error())
+	}
+
+	if err = ctx.GetStub().PutState(assetId, contractAsBytes); err != nil {
+		return fmt.Errorf("failed to put to world state: %s", err.Error())
+	}
+
+	if transactionContext, ok := ctx.(*TransactionContext); ok {
+		delete(transactionContext.states, assetId)
+	}
+
+	return nil
+}
+
+func (s *SmartContract) getState(ctx contractapi.TransactionContextInterface, assetId string) ([]byte, error) {
+	transactionContext, ok := ctx.(*TransactionContext)
+
+	if ok {
+		if contractAsBytes, cached := transactionContext.states[assetId]; cached {
+			return contractAsBytes, nil
+		}
+	}
+
+	contractAsBytes, err := ctx.GetStub().GetState(assetId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if ok && contractAsBytes != nil {
+		if transactionContext.states == nil {
+			transactionContext.states = make(map[string][]byte)
+		}
+
+		transactionContext.states[assetId] = contractAsBytes
+	}
+
+	return contractAsBytes, nil
+}
+
+func (s *SmartContract) readClauseUsage(ctx contractapi.TransactionContextInterface, assetId string, clause string) (*ClauseUsage, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(usageObjectType, []string{assetId, clause})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create usage key: %s", err.Error())
+	}
+
+	usageAsBytes, err := ctx.GetStub().GetState(key)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from state: %s", err.Error())
+	}
+
+	usage := &ClauseUsage{LastRequestAt: make(map[string]time.Time)}
+
+	if usageAsBytes == nil {
+		return usage, nil
+	}
+
+	if err = json.Unmarshal(usageAsBytes, usage); err != nil {
+		return nil, fmt.Errorf("marshal error: %s", err.Error())
+	}
+
+	if usage.LastRequestAt == nil {
+		usage.LastRequestAt = make(map[string]time.Time)
+	}
+
+	return usage, nil
+}
+
+func (s *SmartContract) putClauseUsage(ctx contractapi.TransactionContextInterface, assetId string, clause string, usage *ClauseUsage) error {
+	key, err := ctx.GetStub().CreateCompositeKey(usageObjectType, []string{assetId, clause})
+
+	if err != nil {
+		return fmt.Errorf("failed to create usage key: %s", err.Error())
+	}
+
+	usageAsBytes, err := json.Marshal(usage)
+
+	if err != nil {
+		return fmt.Errorf("marshal error: %s", err.Error())
+	}
+
+	return ctx.GetStub().PutState(key, usageAsBytes)
+}
+
+func (s *SmartContract) putRequest(ctx contractapi.TransactionContextInterface, assetId string, request *Request) error {
+	key, err := ctx.GetStub().CreateCompositeKey(requestObjectType, []string{assetId, request.Id})
+
+	if err != nil {
+		return fmt.Errorf("failed to create request key: %s", err.Error())
+	}
+
+	requestAsBytes, err := json.Marshal(request)
+
+	if err != nil {
+		return fmt.Errorf("marshal error: %s", err.Error())
+	}
+
+	return ctx.GetStub().PutState(key, requestAsBytes)
+}
+
+func (s *SmartContract) readRequest(ctx contractapi.TransactionContextInterface, assetId string, requestId string) (*Request, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(requestObjectType, []string{assetId, requestId})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request key: %s", err.Error())
+	}
+
+	requestAsBytes, err := ctx.GetStub().GetState(key)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from state: %s", err.Error())
+	}
+
+	if requestAsBytes == nil {
+		return nil, nil
+	}
+
+	request := new(Request)
+
+	if err = json.Unmarshal(requestAsBytes, request); err != nil {
+		return nil, fmt.Errorf("marshal error: %s", err.Error())
+	}
+
+	return request, nil
+}
+
+func (s *SmartContract) getRequest(ctx contractapi.TransactionContextInterface, assetId string, requestId string) (*Request, error) {
+	request, err := s.readRequest(ctx, assetId, requestId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if request == nil {
+		return nil, fmt.Errorf("no request found for %s", requestId)
+	}
+
+	return request, nil
+}
+
+func (s *SmartContract) newAsset(assetRequest AssetRequest) (*Asset, error) {
+	var beginDate time.Time
+	var dueDate time.Time
+	var err error
+
+	if assetRequest.BeginDate == "" {
+		return nil, fmt.Errorf("begin date is required")
+	}
+
+	if beginDate, err = s.string2Time(assetRequest.BeginDate); err != nil {
+		return nil, err
+	}
+
+	if assetRequest.DueDate == "" {
+		return nil, fmt.Errorf("due date is required")
+	}
+
+	if dueDate, err = s.string2Time(assetRequest.DueDate); err != nil {
+		return nil, err
+	}
+
+	if err := s.isBeginDateValid(beginDate); err != nil {
+		return nil, err
+	}
+
+	if err := s.isBeginDateRecent(beginDate); err != nil {
+		return nil, err
+	}
+
+	if err := s.isDueDateValid(dueDate); err != nil {
+		return nil, err
+	}
+
+	if err := s.isDueDateGreaterThanBeginDate(beginDate, dueDate); err != nil {
+		return nil, err
+	}
+
+	if err := s.isApplicationIdValid(assetRequest.Parties.Process.Id); err != nil {
+		return nil, err
+	}
+
+	if err := s.isProcessIdValid(assetRequest.Parties.Process.Id); err != nil {
+		return nil, err
+	}
+
+	if assetRequest.Parties.Application.Id == assetRequest.Parties.Process.Id {
+		return nil, fmt.Errorf("application and process must be different parties")
+	}
+
+	if assetRequest.Quorum != "" && assetRequest.Quorum != QuorumAll && assetRequest.Quorum != QuorumMajority {
+		return nil, fmt.Errorf("unsupported quorum: %s, expected one of %s/%s", assetRequest.Quorum, QuorumAll, QuorumMajority)
+	}
+
+	if assetRequest.GracePeriodSeconds < 0 {
+		return nil, fmt.Errorf("grace period must not be negative")
+	}
+
+	if err := s.isDependsOnValid(assetRequest.DependsOn); err != nil {
+		return nil, err
+	}
+
+	asset := Asset{}
+	parties := Parties{}
+
+	asset.BeginDate = beginDate
+	asset.DueDate = dueDate
+	asset.GracePeriodSeconds = assetRequest.GracePeriodSeconds
+	asset.SchemaVersion = currentSchemaVersion
+
+	parties.Application.Id = assetRequest.Parties.Application.Id
+	parties.Application.Name = assetRequest.Parties.Application.Name
+	parties.Application.IsSigned = false
+
+	parties.Process.Id = assetRequest.Parties.Process.Id
+	parties.Process.Name = assetRequest.Parties.Process.Name
+	parties.Process.IsSigned = false
+
+	asset.Parties = parties
+	asset.CreatedAt = nowFunc().UTC()
+	asset.Requests = make(map[string]Request)
+	asset.Obligations = make(map[string]Obligation)
+
+	for _, obligationRequest := range assetRequest.Obligations {
+		var deadline time.Time
+
+		if _, err := s.partyByRole(&asset, obligationRequest.Party); err != nil {
+			return nil, err
+		}
+
+		if obligationRequest.Description == "" {
+			return nil, fmt.Errorf("obligation description is required")
+		}
+
+		if deadline, err = s.string2Time(obligationRequest.Deadline); err != nil {
+			return nil, err
+		}
+
+		obligationId := uuid.New().String()
+
+		asset.Obligations[obligationId] = Obligation{
+			Id:          obligationId,
+			Party:       obligationRequest.Party,
+			Description: obligationRequest.Description,
+			Deadline:    deadline,
+		}
+	}
+
+	for _, prohibition := range assetRequest.Prohibitions {
+		if _, err := s.partyByRole(&asset, prohibition.Party); err != nil {
+			return nil, err
+		}
+
+		if prohibition.Operation == "" {
+			return nil, fmt.Errorf("prohibition operation is required")
+		}
+	}
+
+	asset.Prohibitions = assetRequest.Prohibitions
+
+	for key := range assetRequest.Tags {
+		if key == "" || strings.ContainsAny(key, ".$") {
+			return nil, fmt.Errorf("invalid tag key: %q", key)
+		}
+	}
+
+	asset.Tags = assetRequest.Tags
+	asset.DocumentHash = assetRequest.DocumentHash
+	asset.DocumentURI = assetRequest.DocumentURI
+	asset.Quorum = assetRequest.Quorum
+	asset.PendingAmendments = make(map[string]Amendment)
+
+	if asset.Quorum == "" {
+		asset.Quorum = QuorumAll
+	}
+
+	asset.DependsOn = assetRequest.DependsOn
+	asset.FiredClauses = make(map[string]time.Time)
+
+	if err := s.applyRightRequestBerthingConfig(&asset, assetRequest.RightRequestBerthing); err != nil {
+		return nil, err
+	}
+
+	asset.ObligationRespondToPortProposal.ObligationRespondToPortProposalTimeout0.Increase = 3600
+
+	if err := s.applyObligationRespondToPortProposalConfig(&asset, assetRequest.ObligationRespondToPortProposal); err != nil {
+		return nil, err
+	}
+
+	if err := s.applyProhibitionNotAllowedRequestBerthingConfig(&asset, assetRequest.ProhibitionNotAllowedRequestBerthing); err != nil {
+		return nil, err
+	}
+
+	asset.ObligationRespondToBerthingRequest.ObligationRespondToBerthingRequestTimeout0.Increase = 3600
+
+	if err := s.applyObligationRespondToBerthingRequestConfig(&asset, assetRequest.ObligationRespondToBerthingRequest); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateClauseConfig(&asset); err != nil {
+		return nil, err
+	}
+
+	return &asset, nil
+}
+
+func (s *SmartContract) applyRightRequestBerthingConfig(asset *Asset, config RightRequestBerthingConfig) error {
+	if config.MinIntervalSeconds < 0 {
+		return fmt.Errorf("min interval must not be negative")
+	}
+
+	if config.CancellationWindowSeconds < 0 {
+		return fmt.Errorf("cancellation window must not be negative")
+	}
+
+	asset.RightRequestBerthing.MinIntervalSeconds = config.MinIntervalSeconds
+	asset.RightRequestBerthing.CancellationWindowSeconds = config.CancellationWindowSeconds
+
+	totalWeight := 0
+
+	for rule, weight := range config.RuleWeights {
+		if !rightRequestBerthingRules[rule] {
+			return fmt.Errorf("unknown rule: %s", rule)
+		}
+
+		if weight < 0 {
+			return fmt.Errorf("weight of rule %s must not be negative", rule)
+		}
+
+		totalWeight += weight
+	}
+
+	if config.RuleWeights != nil && totalWeight == 0 {
+		return fmt.Errorf("rule weights must not all be zero")
+	}
+
+	if config.ScoreThreshold < 0 || config.ScoreThreshold > 1 {
+		return fmt.Errorf("score threshold must be between 0 and 1")
+	}
+
+	asset.RightRequestBerthing.RuleWeights = config.RuleWeights
+	asset.RightRequestBerthing.ScoreThreshold = 1
+
+	if config.ScoreThreshold > 0 {
+		asset.RightRequestBerthing.ScoreThreshold = config.ScoreThreshold
+	}
+
+	asset.RightRequestBerthing.AllowedMSPs = config.AllowedMSPs
+	asset.RightRequestBerthing.BusinessHours = Interval{}
+
+	if config.BusinessHours != nil {
+		businessHours, err := s.parseBusinessHours(*config.BusinessHours)
+
+		if err != nil {
+			return err
+		}
+
+		asset.RightRequestBerthing.BusinessHours = businessHours
+	}
+
+	asset.RightRequestBerthing.Window = Interval{}
+
+	if config.Window != "" {
+		end, err := s.addDuration(asset.BeginDate, config.Window)
+
+		if err != nil {
+			return err
+		}
+
+		asset.RightRequestBerthing.Window = Interval{Start: asset.BeginDate, End: end}
+	}
+
+	return nil
+}
+
+func (s *SmartContract) applyObligationRespondToPortProposalConfig(asset *Asset, config ObligationRespondToPortProposalConfig) error {
+	if config.MinIntervalSeconds < 0 {
+		return fmt.Errorf("min interval must not be negative")
+	}
+
+	if config.DiscountPercentage < 0 || config.DiscountPercentage > 100 {
+		return fmt.Errorf("discount percentage must be between 0 and 100")
+	}
+
+	asset.ObligationRespondToPortProposal.MinIntervalSeconds = config.MinIntervalSeconds
+	asset.ObligationRespondToPortProposal.DiscountPercentage = config.DiscountPercentage
+
+	totalWeight := 0
+
+	for rule, weight := range config.RuleWeights {
+		if !obligationRespondToPortProposalRules[rule] {
+			return fmt.Errorf("unknown rule: %s", rule)
+		}
+
+		if weight < 0 {
+			return fmt.Errorf("weight of rule %s must not be negative", rule)
+		}
+
+		totalWeight += weight
+	}
+
+	if config.RuleWeights != nil && totalWeight == 0 {
+		return fmt.Errorf("rule weights must not all be zero")
+	}
+
+	if config.ScoreThreshold < 0 || config.ScoreThreshold > 1 {
+		return fmt.Errorf("score threshold must be between 0 and 1")
+	}
+
+	asset.ObligationRespondToPortProposal.RuleWeights = config.RuleWeights
+	asset.ObligationRespondToPortProposal.ScoreThreshold = 1
+
+	if config.ScoreThreshold > 0 {
+		asset.ObligationRespondToPortProposal.ScoreThreshold = config.ScoreThreshold
+	}
+
+	asset.ObligationRespondToPortProposal.AllowedMSPs = config.AllowedMSPs
+	asset.ObligationRespondToPortProposal.BusinessHours = Interval{}
+
+	if config.BusinessHours != nil {
+		businessHours, err := s.parseBusinessHours(*config.BusinessHours)
+
+		if err != nil {
+			return err
+		}
+
+		asset.ObligationRespondToPortProposal.BusinessHours = businessHours
+	}
+
+	asset.ObligationRespondToPortProposal.Window = Interval{}
+
+	if config.Window != "" {
+		end, err := s.addDuration(asset.BeginDate, config.Window)
+
+		if err != nil {
+			return err
+		}
+
+		asset.ObligationRespondToPortProposal.Window = Interval{Start: asset.BeginDate, End: end}
+	}
+
+	return nil
+}
+
+func (s *SmartContract) applyProhibitionNotAllowedRequestBerthingConfig(asset *Asset, config ProhibitionNotAllowedRequestBerthingConfig) error {
+	if config.MinIntervalSeconds < 0 {
+		return fmt.Errorf("min interval must not be negative")
+	}
+
+	if config.CancellationWindowSeconds < 0 {
+		return fmt.Errorf("cancellation window must not be negative")
+	}
+
+	asset.ProhibitionNotAllowedRequestBerthing.MinIntervalSeconds = config.MinIntervalSeconds
+	asset.ProhibitionNotAllowedRequestBerthing.CancellationWindowSeconds = config.CancellationWindowSeconds
+
+	totalWeight := 0
+
+	for rule, weight := range config.RuleWeights {
+		if !prohibitionNotAllowedRequestBerthingRules[rule] {
+			return fmt.Errorf("unknown rule: %s", rule)
+		}
+
+		if weight < 0 {
+			return fmt.Errorf("weight of rule %s must not be negative", rule)
+		}
+
+		totalWeight += weight
+	}
+
+	if config.RuleWeights != nil && totalWeight == 0 {
+		return fmt.Errorf("rule weights must not all be zero")
+	}
+
+	if config.ScoreThreshold < 0 || config.ScoreThreshold > 1 {
+		return fmt.Errorf("score threshold must be between 0 and 1")
+	}
+
+	asset.ProhibitionNotAllowedRequestBerthing.RuleWeights = config.RuleWeights
+	asset.ProhibitionNotAllowedRequestBerthing.ScoreThreshold = 1
+
+	if config.ScoreThreshold > 0 {
+		asset.ProhibitionNotAllowedRequestBerthing.ScoreThreshold = config.ScoreThreshold
+	}
+
+	asset.ProhibitionNotAllowedRequestBerthing.AllowedMSPs = config.AllowedMSPs
+	asset.ProhibitionNotAllowedRequestBerthing.BusinessHours = Interval{}
+
+	if config.BusinessHours != nil {
+		businessHours, err := s.parseBusinessHours(*config.BusinessHours)
+
+		if err != nil {
+			return err
+		}
+
+		asset.ProhibitionNotAllowedRequestBerthing.BusinessHours = businessHours
+	}
+
+	asset.ProhibitionNotAllowedRequestBerthing.Window = Interval{}
+
+	if config.Window != "" {
+		end, err := s.addDuration(asset.BeginDate, config.Window)
+
+		if err != nil {
+			return err
+		}
+
+		asset.ProhibitionNotAllowedRequestBerthing.Window = Interval{Start: asset.BeginDate, End: end}
+	}
+
+	return nil
+}
+
+func (s *SmartContract) applyObligationRespondToBerthingRequestConfig(asset *Asset, config ObligationRespondToBerthingRequestConfig) error {
+	if config.MinIntervalSeconds < 0 {
+		return fmt.Errorf("min interval must not be negative")
+	}
+
+	if config.DiscountPercentage < 0 || config.DiscountPercentage > 100 {
+		return fmt.Errorf("discount percentage must be between 0 and 100")
+	}
+
+	asset.ObligationRespondToBerthingRequest.MinIntervalSeconds = config.MinIntervalSeconds
+	asset.ObligationRespondToBerthingRequest.DiscountPercentage = config.DiscountPercentage
+
+	totalWeight := 0
+
+	for rule, weight := range config.RuleWeights {
+		if !obligationRespondToBerthingRequestRules[rule] {
+			return fmt.Errorf("unknown rule: %s", rule)
+		}
+
+		if weight < 0 {
+			return fmt.Errorf("weight of rule %s must not be negative", rule)
+		}
+
+		totalWeight += weight
+	}
+
+	if config.RuleWeights != nil && totalWeight == 0 {
+		return fmt.Errorf("rule weights must not all be zero")
+	}
+
+	if config.ScoreThreshold < 0 || config.ScoreThreshold > 1 {
+		return fmt.Errorf("score threshold must be between 0 and 1")
+	}
+
+	asset.ObligationRespondToBerthingRequest.RuleWeights = config.RuleWeights
+	asset.ObligationRespondToBerthingRequest.ScoreThreshold = 1
+
+	if config.ScoreThreshold > 0 {
+		asset.ObligationRespondToBerthingRequest.ScoreThreshold = config.ScoreThreshold
+	}
+
+	asset.ObligationRespondToBerthingRequest.AllowedMSPs = config.AllowedMSPs
+	asset.ObligationRespondToBerthingRequest.BusinessHours = Interval{}
+
+	if config.BusinessHours != nil {
+		businessHours, err := s.parseBusinessHours(*config.BusinessHours)
+
+		if err != nil {
+			return err
+		}
+
+		asset.ObligationRespondToBerthingRequest.BusinessHours = businessHours
+	}
+
+	asset.ObligationRespondToBerthingRequest.Window = Interval{}
+
+	if config.Window != "" {
+		end, err := s.addDuration(asset.BeginDate, config.Window)
+
+		if err != nil {
+			return err
+		}
+
+		asset.ObligationRespondToBerthingRequest.Window = Interval{Start: asset.BeginDate, End: end}
+	}
+
+	return nil
+}
+
+func (s *SmartContract) validateClauseConfig(asset *Asset) error {
+	if !asset.RightRequestBerthing.Window.End.IsZero() && asset.RightRequestBerthing.Window.End.After(asset.DueDate) {
+		return fmt.Errorf("RightRequestBerthing: clause window must end by the due date")
+	}
+
+	if !asset.ObligationRespondToPortProposal.Window.End.IsZero() && asset.ObligationRespondToPortProposal.Window.End.After(asset.DueDate) {
+		return fmt.Errorf("ObligationRespondToPortProposal: clause window must end by the due date")
+	}
+
+	if !asset.ProhibitionNotAllowedRequestBerthing.Window.End.IsZero() && asset.ProhibitionNotAllowedRequestBerthing.Window.End.After(asset.DueDate) {
+		return fmt.Errorf("ProhibitionNotAllowedRequestBerthing: clause window must end by the due date")
+	}
+
+	if !asset.ObligationRespondToBerthingRequest.Window.End.IsZero() && asset.ObligationRespondToBerthingRequest.Window.End.After(asset.DueDate) {
+		return fmt.Errorf("ObligationRespondToBerthingRequest: clause window must end by the due date")
+	}
+
+	return nil
+}
+
+func (s *SmartContract) isClauseConfigMutable(asset *Asset) error {
+	if asset.IsSigned {
+		return fmt.Errorf("cannot modify clause config after signing")
+	}
+
+	return nil
+}
+
+func (s *SmartContract) UpdateClauseConfig(ctx contractapi.TransactionContextInterface, assetId string, clause string, configJSON string) error {
+
+	var id string
+	var err error
+	var asset *Asset
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return err
+	}
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return err
+	}
+
+	if _, err := s.isParty(id, asset); err != nil {
+		return err
+	}
+
+	if err := s.isClauseConfigMutable(asset); err != nil {
+		return err
+	}
+
+	switch clause {
+	case "RightRequestBerthing":
+		var config RightRequestBerthingConfig
+
+		if err = s.decodeArgs(configJSON, &config); err != nil {
+			return fmt.Errorf("invalid config for clause %s: %s", clause, err.Error())
+		}
+
+		if err = s.applyRightRequestBerthingConfig(asset, config); err != nil {
+			return err
+		}
+	case "ObligationRespondToPortProposal":
+		var config ObligationRespondToPortProposalConfig
+
+		if err = s.decodeArgs(configJSON, &config); err != nil {
+			return fmt.Errorf("invalid config for clause %s: %s", clause, err.Error())
+		}
+
+		if err = s.applyObligationRespondToPortProposalConfig(asset, config); err != nil {
+			return err
+		}
+	case "ProhibitionNotAllowedRequestBerthing":
+		var config ProhibitionNotAllowedRequestBerthingConfig
+
+		if err = s.decodeArgs(configJSON, &config); err != nil {
+			return fmt.Errorf("invalid config for clause %s: %s", clause, err.Error())
+		}
+
+		if err = s.applyProhibitionNotAllowedRequestBerthingConfig(asset, config); err != nil {
+			return err
+		}
+	case "ObligationRespondToBerthingRequest":
+		var config ObligationRespondToBerthingRequestConfig
+
+		if err = s.decodeArgs(configJSON, &config); err != nil {
+			return fmt.Errorf("invalid config for clause %s: %s", clause, err.Error())
+		}
+
+		if err = s.applyObligationRespondToBerthingRequestConfig(asset, config); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown clause: %s", clause)
+	}
+
+	if err = s.validateClauseConfig(asset); err != nil {
+		return err
+	}
+
+	return s.putState(ctx, assetId, asset)
+}
+
+func (s *SmartContract) SetDocument(ctx contractapi.TransactionContextInterface, assetId string, documentHash string, documentURI string) error {
+
+	var id string
+	var err error
+	var asset *Asset
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return err
+	}
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return err
+	}
+
+	if _, err := s.isParty(id, asset); err != nil {
+		return err
+	}
+
+	if asset.IsSigned {
+		return fmt.Errorf("cannot modify document after signing")
+	}
+
+	asset.DocumentHash = documentHash
+	asset.DocumentURI = documentURI
+
+	return s.putState(ctx, assetId, asset)
+}
+
+func (s *SmartContract) Init(ctx contractapi.TransactionContextInterface, assetRequest AssetRequest) (string, error) {
+	var id string
+	var err error
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return "", err
+	}
+
+	return s.createAsset(ctx, id, assetRequest)
+}
+
+func (s *SmartContract) InitBatch(ctx contractapi.TransactionContextInterface, assetRequests []AssetRequest) ([]InitResult, error) {
+	var id string
+	var err error
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return nil, err
+	}
+
+	results := make([]InitResult, len(assetRequests))
+	seen := make(map[string]bool)
+
+	for i, assetRequest := range assetRequests {
+		if assetRequest.Id != "" && seen[assetRequest.Id] {
+			results[i] = InitResult{Error: fmt.Sprintf("asset %s already exists", assetRequest.Id)}
+			continue
+		}
+
+		assetId, err := s.createAsset(ctx, id, assetRequest)
+
+		if err != nil {
+			results[i] = InitResult{Error: err.Error()}
+			continue
+		}
+
+		seen[assetId] = true
+		results[i] = InitResult{Id: assetId}
+	}
+
+	return results, nil
+}
+
+func (s *SmartContract) deterministicAssetId(asset *Asset) string {
+	hash := sha256.Sum256([]byte(strings.Join([]string{
+		asset.Parties.Application.Id,
+		asset.Parties.Process.Id,
+		asset.BeginDate.Format(time.RFC3339Nano),
+		asset.DueDate.Format(time.RFC3339Nano),
+	}, "\x00")))
+
+	return hex.EncodeToString(hash[:])
+}
+
+func (s *SmartContract) createAsset(ctx contractapi.TransactionContextInterface, id string, assetRequest AssetRequest) (string, error) {
+	var asset *Asset
+	var err error
+
+	if asset, err = s.newAsset(assetRequest); err != nil {
+		return "", err
+	}
+
+	if _, err := s.isParty(id, asset); err != nil {
+		return "", err
+	}
+
+	assetId := assetRequest.Id
+
+	if assetId == "" && deterministicAssetIds {
+		assetId = s.deterministicAssetId(asset)
+
+		var exists bool
+
+		if exists, err = s.AssetExists(ctx, assetId); err != nil {
+			return "", err
+		}
+
+		if exists {
+			return assetId, nil
+		}
+	} else if assetId == "" {
+		assetId = uuid.New().String()
+	} else {
+		var exists bool
+
+		if exists, err = s.AssetExists(ctx, assetId); err != nil {
+			return "", err
+		}
+
+		if exists {
+			return "", fmt.Errorf("asset %s already exists", assetId)
+		}
+	}
+
+	asset.Id = assetId
+	asset.CreatedBy = id
+
+	if err = s.putState(ctx, assetId, asset); err != nil {
+		return "", err
+	}
+
+	return assetId, nil
+}
+
+func (s *SmartContract) Renew(ctx contractapi.TransactionContextInterface, assetId string, newBeginDate string, newDueDate string) (string, error) {
+
+	var id string
+	var err error
+	var asset *Asset
+	var renewed *Asset
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return "", err
+	}
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return "", err
+	}
+
+	if _, err := s.isParty(id, asset); err != nil {
+		return "", err
+	}
+
+	if err := s.isCancelled(asset); err != nil {
+		return "", err
+	}
+
+	if asset.DueDate.After(nowFunc().UTC().Add(renewalWindow)) {
+		return "", fmt.Errorf("the asset can only be renewed when expired or near expiry")
+	}
+
+	assetRequest := AssetRequest{
+		BeginDate: newBeginDate,
+		DueDate:   newDueDate,
+		Parties: PartiesRequest{
+			Application: PartyRequest{Name: asset.Parties.Application.Name, Id: asset.Parties.Application.Id},
+			Process:     PartyRequest{Name: asset.Parties.Process.Name, Id: asset.Parties.Process.Id},
+		},
+		GracePeriodSeconds: asset.GracePeriodSeconds,
+		Prohibitions:       asset.Prohibitions,
+		DependsOn:          asset.DependsOn,
+		Quorum:             asset.Quorum,
+	}
+
+	if renewed, err = s.newAsset(assetRequest); err != nil {
+		return "", err
+	}
+
+	// the clause config is carried over as stored; only the clause window moves with the new dates
+	renewed.RightRequestBerthing = asset.RightRequestBerthing
+	renewed.RightRequestBerthing.Window = s.shiftWindow(asset.RightRequestBerthing.Window, asset.BeginDate, renewed.BeginDate)
+
+	renewed.ObligationRespondToPortProposal = asset.ObligationRespondToPortProposal
+	renewed.ObligationRespondToPortProposal.Window = s.shiftWindow(asset.ObligationRespondToPortProposal.Window, asset.BeginDate, renewed.BeginDate)
+
+	renewed.ProhibitionNotAllowedRequestBerthing = asset.ProhibitionNotAllowedRequestBerthing
+	renewed.ProhibitionNotAllowedRequestBerthing.Window = s.shiftWindow(asset.ProhibitionNotAllowedRequestBerthing.Window, asset.BeginDate, renewed.BeginDate)
+
+	renewed.ObligationRespondToBerthingRequest = asset.ObligationRespondToBerthingRequest
+	renewed.ObligationRespondToBerthingRequest.Window = s.shiftWindow(asset.ObligationRespondToBerthingRequest.Window, asset.BeginDate, renewed.BeginDate)
+
+	if err = s.validateClauseConfig(renewed); err != nil {
+		return "", err
+	}
+
+	renewedAssetId := uuid.New().String()
+
+	renewed.Id = renewedAssetId
+	renewed.CreatedBy = id
+	renewed.PreviousAssetId = assetId
+
+	if err = s.putState(ctx, renewedAssetId, renewed); err != nil {
+		return "", err
+	}
+
+	return renewedAssetId, nil
+}
+
+func (s *SmartContract) Sign(ctx contractapi.TransactionContextInterface, assetId string, signatureRef string) error {
+
+	var id string
+	var err error
+	var asset *Asset
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return err
+	}
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return err
+	}
+
+	if _, err := s.isParty(id, asset); err != nil {
+		return err
+	}
+
+	if err = s.signAsset(id, asset, signatureRef); err != nil {
+		return err
+	}
+
+	if err = s.putState(ctx, assetId, asset); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *SmartContract) signAsset(id string, asset *Asset, signatureRef string) error {
+
+	if err := s.canSign(asset); err != nil {
+		return err
+	}
+
+	if asset.Parties.Application.Id == id {
+
+		if _, err := s.isSigned(asset.Parties.Application); err != nil {
+			return err
+		}
+
+		asset.Parties.Application.IsSigned = true
+		asset.Parties.Application.SignatureDate = nowFunc().UTC()
+		asset.Parties.Application.SignatureRef = signatureRef
+
+		asset.SignatureLog = append(asset.SignatureLog, SignatureEntry{
+			ClientId: id,
+			Role:     RoleApplication,
+			SignedAt: asset.Parties.Application.SignatureDate,
+		})
+	}
+
+	if asset.Parties.Process.Id == id {
+
+		if _, err := s.isSigned(asset.Parties.Process); err != nil {
+			return err
+		}
+
+		asset.Parties.Process.IsSigned = true
+		asset.Parties.Process.SignatureDate = nowFunc().UTC()
+		asset.Parties.Process.SignatureRef = signatureRef
+
+		asset.SignatureLog = append(asset.SignatureLog, SignatureEntry{
+			ClientId: id,
+			Role:     RoleProcess,
+			SignedAt: asset.Parties.Process.SignatureDate,
+		})
+	}
+
+	asset.IsSigned = asset.Parties.Application.IsSigned && asset.Parties.Process.IsSigned
+
+	return nil
+}
+
+func (s *SmartContract) SignBatch(ctx contractapi.TransactionContextInterface, assetIds []string) (map[string]SignResult, error) {
+
+	var id string
+	var err error
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]SignResult)
+
+	for _, assetId := range assetIds {
+		asset, err := s.QueryAsset(ctx, assetId)
+
+		if err != nil {
+			results[assetId] = SignResult{Status: SignResultError, Error: err.Error()}
+			continue
+		}
+
+		if _, err := s.isParty(id, asset); err != nil {
+			results[assetId] = SignResult{Status: SignResultNotAParty, Error: err.Error()}
+			continue
+		}
+
+		if (asset.Parties.Application.Id == id && asset.Parties.Application.IsSigned) || (asset.Parties.Process.Id == id && asset.Parties.Process.IsSigned) {
+			results[assetId] = SignResult{Status: SignResultAlreadySigned}
+			continue
+		}
+
+		if err := s.signAsset(id, asset, ""); err != nil {
+			results[assetId] = SignResult{Status: SignResultError, Error: err.Error()}
+			continue
+		}
+
+		if err := s.putState(ctx, assetId, asset); err != nil {
+			results[assetId] = SignResult{Status: SignResultError, Error: err.Error()}
+			continue
+		}
+
+		results[assetId] = SignResult{Status: SignResultSigned}
+	}
+
+	return results, nil
+}
+
+func (s *SmartContract) GetPendingApprovals(ctx contractapi.TransactionContextInterface, assetId string) ([]PendingApproval, error) {
+
+	asset, err := s.QueryAsset(ctx, assetId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []PendingApproval{}
+
+	for _, amendment := range asset.PendingAmendments {
+		if len(amendment.Approvals) == 0 || s.isQuorumReached(asset, len(amendment.Approvals)) {
+			continue
+		}
+
+		approved := make(map[string]bool)
+
+		for _, approval := range amendment.Approvals {
+			approved[approval] = true
+		}
+
+		awaiting := []string{}
+
+		for _, partyId := range []string{asset.Parties.Application.Id, asset.Parties.Process.Id} {
+			if !approved[partyId] {
+				awaiting = append(awaiting, partyId)
+			}
+		}
+
+		pending = append(pending, PendingApproval{
+			Operation:  amendment.Operation,
+			Value:      amendment.Value,
+			ApprovedBy: amendment.Approvals,
+			AwaitingBy: awaiting,
+			CreatedAt:  amendment.CreatedAt,
+		})
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Operation < pending[j].Operation
+	})
+
+	return pending, nil
+}
+
+func (s *SmartContract) WithdrawApproval(ctx contractapi.TransactionContextInterface, assetId string, op string) error {
+
+	var id string
+	var err error
+	var asset *Asset
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return err
+	}
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return err
+	}
+
+	if _, err := s.isParty(id, asset); err != nil {
+		return err
+	}
+
+	amendment, exists := asset.PendingAmendments[op]
+
+	if !exists {
+		return fmt.Errorf("no pending amendment found for %s", op)
+	}
+
+	approvals := []string{}
+
+	for _, approval := range amendment.Approvals {
+		if approval != id {
+			approvals = append(approvals, approval)
+		}
+	}
+
+	if len(approvals) == len(amendment.Approvals) {
+		return fmt.Errorf("amendment %s has not been approved by this party", op)
+	}
+
+	if len(approvals) == 0 {
+		delete(asset.PendingAmendments, op)
+	} else {
+		amendment.Approvals = approvals
+		asset.PendingAmendments[op] = amendment
+	}
+
+	return s.putState(ctx, assetId, asset)
+}
+
+func (s *SmartContract) ReplacePartyIdentity(ctx contractapi.TransactionContextInterface, assetId string, role string, newId string) (bool, error) {
+
+	var id string
+	var err error
+	var asset *Asset
+	var party *Party
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return false, err
+	}
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return false, err
+	}
+
+	if _, err := s.isParty(id, asset); err != nil {
+		return false, err
+	}
+
+	if err := s.isCancelled(asset); err != nil {
+		return false, err
+	}
+
+	if party, err = s.partyByRole(asset, role); err != nil {
+		return false, err
+	}
+
+	if newId == "" {
+		return false, fmt.Errorf("new identity must not be empty")
+	}
+
+	if newId == asset.Parties.Application.Id || newId == asset.Parties.Process.Id {
+		return false, fmt.Errorf("identity %s is already a party of this contract", newId)
+	}
+
+	operation := replacePartyIdentityOperation + ":" + role
+
+	if asset.PendingAmendments == nil {
+		asset.PendingAmendments = make(map[string]Amendment)
+	}
+
+	if amendment, exists := asset.PendingAmendments[operation]; !exists || amendment.Value != newId {
+		asset.PendingAmendments[operation] = Amendment{
+			Operation:             operation,
+			Value:                 newId,
+			Approvals:             []string{},
+			CreatedAt:             nowFunc().UTC(),
+			InvalidatesSignatures: invalidatesSignatures[replacePartyIdentityOperation],
+		}
+	}
+
+	applied, err := s.recordApproval(asset, operation, id)
+
+	if err != nil {
+		return false, err
+	}
+
+	if applied {
+		party.Id = newId
+
+		if asset.PendingAmendments[operation].InvalidatesSignatures {
+			s.resetSignatures(asset)
+		}
+
+		delete(asset.PendingAmendments, operation)
+	}
+
+	if err = s.putState(ctx, assetId, asset); err != nil {
+		return false, err
+	}
+
+	return applied, nil
+}
+
+func (s *SmartContract) Cancel(ctx contractapi.TransactionContextInterface, assetId string, reason string) error {
+
+	var id string
+	var err error
+	var asset *Asset
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return err
+	}
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return err
+	}
+
+	if _, err := s.isParty(id, asset); err != nil {
+		return err
+	}
+
+	if err := s.isCancelled(asset); err != nil {
+		return err
+	}
+
+	if asset.IsSigned {
+		return fmt.Errorf("only unsigned contracts can be cancelled")
+	}
+
+	asset.Cancelled = true
+	asset.CancelledReason = reason
+	asset.CancelledAt = nowFunc().UTC()
+
+	return s.putState(ctx, assetId, asset)
+}
+
+func (s *SmartContract) ExtendDueDate(ctx contractapi.TransactionContextInterface, assetId string, newDueDate string) (bool, error) {
+
+	var id string
+	var err error
+	var asset *Asset
+	var dueDate time.Time
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return false, err
+	}
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return false, err
+	}
+
+	if _, err := s.isParty(id, asset); err != nil {
+		return false, err
+	}
+
+	if err := s.isTerminated(asset); err != nil {
+		return false, err
+	}
+
+	if err := s.isCancelled(asset); err != nil {
+		return false, err
+	}
+
+	if dueDate, err = s.string2Time(newDueDate); err != nil {
+		return false, err
+	}
+
+	if !dueDate.After(asset.DueDate) {
+		return false, fmt.Errorf("new due date must be after the current due date")
+	}
+
+	if asset.PendingAmendments == nil {
+		asset.PendingAmendments = make(map[string]Amendment)
+	}
+
+	if amendment, exists := asset.PendingAmendments[extendDueDateOperation]; !exists || amendment.Value != newDueDate {
+		asset.PendingAmendments[extendDueDateOperation] = Amendment{
+			Operation:             extendDueDateOperation,
+			Value:                 newDueDate,
+			Approvals:             []string{},
+			CreatedAt:             nowFunc().UTC(),
+			InvalidatesSignatures: invalidatesSignatures[extendDueDateOperation],
+		}
+	}
+
+	applied, err := s.recordApproval(asset, extendDueDateOperation, id)
+
+	if err != nil {
+		return false, err
+	}
+
+	if applied {
+		asset.DueDateHistory = append(asset.DueDateHistory, DateChange{
+			Old:       asset.DueDate,
+			New:       dueDate,
+			ChangedBy: id,
+			ChangedAt: nowFunc().UTC(),
+		})
+
+		asset.DueDate = dueDate
+
+		if asset.PendingAmendments[extendDueDateOperation].InvalidatesSignatures {
+			s.resetSignatures(asset)
+		}
+
+		delete(asset.PendingAmendments, extendDueDateOperation)
+	}
+
+	if err = s.putState(ctx, assetId, asset); err != nil {
+		return false, err
+	}
+
+	return applied, nil
+}
+
+// Terminate is an amendment: each party's call records an approval, and the contract is only
+// terminated once the asset's quorum is reached.
+func (s *SmartContract) Terminate(ctx contractapi.TransactionContextInterface, assetId string, reason string) (bool, error) {
+
+	var id string
+	var err error
+	var asset *Asset
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return false, err
+	}
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return false, err
+	}
+
+	if _, err := s.isParty(id, asset); err != nil {
+		return false, err
+	}
+
+	if err := s.isCancelled(asset); err != nil {
+		return false, err
+	}
+
+	if err := s.isTerminated(asset); err != nil {
+		return false, err
+	}
+
+	if asset.PendingAmendments == nil {
+		asset.PendingAmendments = make(map[string]Amendment)
+	}
+
+	if amendment, exists := asset.PendingAmendments[terminateOperation]; !exists || amendment.Value != reason {
+		asset.PendingAmendments[terminateOperation] = Amendment{
+			Operation: terminateOperation,
+			Value:     reason,
+			Approvals: []string{},
+			CreatedAt: nowFunc().UTC(),
+		}
+	}
+
+	applied, err := s.recordApproval(asset, terminateOperation, id)
+
+	if err != nil {
+		return false, err
+	}
+
+	if applied {
+		asset.Terminated = true
+		asset.TerminatedReason = reason
+		asset.TerminatedAt = nowFunc().UTC()
+
+		delete(asset.PendingAmendments, terminateOperation)
+	}
+
+	if err = s.putState(ctx, assetId, asset); err != nil {
+		return false, err
+	}
+
+	return applied, nil
+}
+
+func (s *SmartContract) Suspend(ctx contractapi.TransactionContextInterface, assetId string) error {
+
+	var id string
+	var err error
+	var asset *Asset
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return err
+	}
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return err
+	}
+
+	if _, err := s.isParty(id, asset); err != nil {
+		return err
+	}
+
+	if err := s.isSuspended(asset); err != nil {
+		return err
+	}
+
+	asset.Suspended = true
+	asset.SuspendedAt = nowFunc().UTC()
+
+	return s.putState(ctx, assetId, asset)
+}
+
+func (s *SmartContract) Resume(ctx contractapi.TransactionContextInterface, assetId string) error {
+
+	var id string
+	var err error
+	var asset *Asset
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return err
+	}
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return err
+	}
+
+	if _, err := s.isParty(id, asset); err != nil {
+		return err
+	}
+
+	if !asset.Suspended {
+		return fmt.Errorf("contract is not suspended")
+	}
+
+	asset.Suspended = false
+	asset.ResumedAt = nowFunc().UTC()
+
+	return s.putState(ctx, assetId, asset)
+}
+
+func (s *SmartContract) QueryAsset(ctx contractapi.TransactionContextInterface, assetId string) (*Asset, error) {
+	return s.queryAsset(ctx, assetId, false)
+}
+
+func (s *SmartContract) GetAsset(ctx contractapi.TransactionContextInterface, assetId string, includeDeleted bool) (*Asset, error) {
+	return s.queryAsset(ctx, assetId, includeDeleted)
+}
+
+func (s *SmartContract) GetAllAssets(ctx contractapi.TransactionContextInterface, includeDeleted bool) ([]*Asset, error) {
+
+	assets := []*Asset{}
+
+	err := s.forEachAsset(ctx, includeDeleted, func(asset *Asset) error {
+		assets = append(assets, asset)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return assets, nil
+}
+
+func (s *SmartContract) ArchiveAsset(ctx contractapi.TransactionContextInterface, assetId string) error {
+
+	var id string
+	var err error
+	var asset *Asset
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return err
+	}
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return err
+	}
+
+	if _, err := s.isParty(id, asset); err != nil {
+		return err
+	}
+
+	asset.Deleted = true
+	asset.DeletedBy = id
+	asset.DeletedAt = nowFunc().UTC()
+
+	return s.putState(ctx, assetId, asset)
+}
+
+func (s *SmartContract) queryAsset(ctx contractapi.TransactionContextInterface, assetId string, includeDeleted bool) (*Asset, error) {
+
+	contractAsBytes, err := s.getState(ctx, assetId)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from state: %s", err.Error())
+	}
+
+	if contractAsBytes == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAssetNotFound, assetId)
+	}
+
+	asset := new(Asset)
+
+	err = json.Unmarshal(contractAsBytes, asset)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrCorruptAsset, assetId, err.Error())
+	}
+
+	if asset.Deleted && !includeDeleted {
+		return nil, fmt.Errorf("%w: %s", ErrAssetNotFound, assetId)
+	}
+
+	if restrictReads {
+		clientId, err := s.QueryClientId(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := s.isParty(clientId, asset); err != nil {
+			return nil, err
+		}
+	}
+
+	s.migrateAsset(asset)
+
+	return asset, nil
+}
+
+// notifyExpiration must only be called from submitted transactions: an evaluated query
+// never commits ExpiredNotified, so the event would fire again on every read. It reports
+// whether this call emitted the event.
+func (s *SmartContract) notifyExpiration(ctx contractapi.TransactionContextInterface, assetId string, asset *Asset) (bool, error) {
+	if asset.ExpiredNotified || !asset.DueDate.Before(nowFunc().UTC()) || !s.canEmitEvent(ctx) {
+		return false, nil
+	}
+
+	asset.ExpiredNotified = true
+
+	payload, err := json.Marshal(ContractExpiredPayload{AssetId: assetId, DueDate: asset.DueDate})
+
+	if err != nil {
+		return false, fmt.Errorf("marshal error: %s", err.Error())
+	}
+
+	if err = s.emitEvent(ctx, assetId, asset, contractExpiredEvent, payload); err != nil {
+		return false, err
+	}
+
+	if err = s.putState(ctx, assetId, asset); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// canExecuteClauseOrNotify runs canExecuteClause after giving the asset the chance to emit
+// ContractExpired. A transaction that returns an error commits nothing, so when this call
+// emitted the event and the contract is past its grace period, the rejection is returned
+// as a failed result instead: the event and ExpiredNotified then commit with it.
+func (s *SmartContract) canExecuteClauseOrNotify(ctx contractapi.TransactionContextInterface, assetId string, asset *Asset) (*ValidationResult, error) {
+	notified, err := s.notifyExpiration(ctx, assetId, asset)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.canExecuteClause(asset); err != nil {
+		if notified && errors.Is(err, ErrAssetExpired) {
+			return &ValidationResult{Valid: false, FailedRules: []string{contractExpiredRule}}, nil
+		}
+
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (s *SmartContract) NotifyExpiration(ctx contractapi.TransactionContextInterface, assetId string) (bool, error) {
+
+	asset, err := s.QueryAsset(ctx, assetId)
+
+	if err != nil {
+		return false, err
+	}
+
+	return s.notifyExpiration(ctx, assetId, asset)
+}
+
+// canEmitEvent reports whether the transaction can still set its event. Fabric keeps only
+// the last SetEvent of a transaction, so each transaction emits at most one.
+func (s *SmartContract) canEmitEvent(ctx contractapi.TransactionContextInterface) bool {
+	transactionContext, ok := ctx.(*TransactionContext)
+
+	return !ok || !transactionContext.eventSet
+}
+
+func (s *SmartContract) emitEvent(ctx contractapi.TransactionContextInterface, assetId string, asset *Asset, name string, payload []byte) error {
+	if !s.canEmitEvent(ctx) {
+		return fmt.Errorf("an event was already emitted in this transaction")
+	}
+
+	asset.EventSeq++
+
+	event := ContractEvent{
+		Seq:       asset.EventSeq,
+		Name:      name,
+		Payload:   string(payload),
+		TxId:      ctx.GetStub().GetTxID(),
+		CreatedAt: nowFunc().UTC(),
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(eventObjectType, []string{assetId, fmt.Sprintf("%010d", event.Seq)})
+
+	if err != nil {
+		return fmt.Errorf("failed to create event key: %s", err.Error())
+	}
+
+	eventAsBytes, err := json.Marshal(event)
+
+	if err != nil {
+		return fmt.Errorf("marshal error: %s", err.Error())
+	}
+
+	if err = ctx.GetStub().PutState(key, eventAsBytes); err != nil {
+		return fmt.Errorf("failed to put to world state: %s", err.Error())
+	}
+
+	if err = ctx.GetStub().SetEvent(name, payload); err != nil {
+		return fmt.Errorf("failed to set event: %s", err.Error())
+	}
+
+	if transactionContext, ok := ctx.(*TransactionContext); ok {
+		transactionContext.eventSet = true
+	}
+
+	return nil
+}
+
+func (s *SmartContract) GetEvents(ctx contractapi.TransactionContextInterface, assetId string, sinceSeq int) ([]*ContractEvent, error) {
+
+	if err := s.checkReadAccess(ctx, assetId); err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(eventObjectType, []string{assetId})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from state: %s", err.Error())
+	}
+
+	defer resultsIterator.Close()
+
+	events := []*ContractEvent{}
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from state: %s", err.Error())
+		}
+
+		event := new(ContractEvent)
+
+		if err = json.Unmarshal(queryResponse.Value, event); err != nil {
+			return nil, fmt.Errorf("marshal error: %s", err.Error())
+		}
+
+		if event.Seq > sinceSeq {
+			events = append(events, event)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Seq < events[j].Seq
+	})
+
+	return events, nil
+}
+
+func (s *SmartContract) migrateAsset(asset *Asset) {
+	if asset.SchemaVersion >= currentSchemaVersion {
+		return
+	}
+
+	if asset.Requests == nil {
+		asset.Requests = make(map[string]Request)
+	}
+
+	if asset.GracePeriodSeconds < 0 {
+		asset.GracePeriodSeconds = 0
+	}
+
+	if asset.RightRequestBerthing.ScoreThreshold == 0 {
+		asset.RightRequestBerthing.ScoreThreshold = 1
+	}
+
+	if asset.ObligationRespondToPortProposal.ScoreThreshold == 0 {
+		asset.ObligationRespondToPortProposal.ScoreThreshold = 1
+	}
+
+	if asset.ProhibitionNotAllowedRequestBerthing.ScoreThreshold == 0 {
+		asset.ProhibitionNotAllowedRequestBerthing.ScoreThreshold = 1
+	}
+
+	if asset.ObligationRespondToBerthingRequest.ScoreThreshold == 0 {
+		asset.ObligationRespondToBerthingRequest.ScoreThreshold = 1
+	}
+
+	asset.SchemaVersion = currentSchemaVersion
+}
+
+// readerId returns the caller an asset listing must be filtered by, or "" when reads are public.
+func (s *SmartContract) readerId(ctx contractapi.TransactionContextInterface) (string, error) {
+	if !restrictReads {
+		return "", nil
+	}
+
+	return s.QueryClientId(ctx)
+}
+
+func (s *SmartContract) canRead(readerId string, asset *Asset) bool {
+	return readerId == "" || readerId == asset.Parties.Application.Id || readerId == asset.Parties.Process.Id
+}
+
+// checkReadAccess guards the read paths that bypass queryAsset, such as raw bytes and the
+// request and event indexes.
+func (s *SmartContract) checkReadAccess(ctx contractapi.TransactionContextInterface, assetId string) error {
+	if !restrictReads {
+		return nil
+	}
+
+	_, err := s.queryAsset(ctx, assetId, true)
+
+	return err
+}
+
+func (s *SmartContract) iteratorToAssets(ctx contractapi.TransactionContextInterface, resultsIterator shim.StateQueryIteratorInterface, includeDeleted bool) ([]*Asset, error) {
+	assets := []*Asset{}
+
+	readerId, err := s.readerId(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from state: %s", err.Error())
+		}
+
+		asset := new(Asset)
+
+		if err = json.Unmarshal(queryResponse.Value, asset); err != nil {
+			return nil, fmt.Errorf("%w: %s: %s", ErrCorruptAsset, queryResponse.Key, err.Error())
+		}
+
+		if asset.Deleted && !includeDeleted {
+			continue
+		}
+
+		if !s.canRead(readerId, asset) {
+			continue
+		}
+
+		asset.Id = queryResponse.Key
+
+		s.migrateAsset(asset)
+
+		assets = append(assets, asset)
+	}
+
+	return assets, nil
+}
+
+func (s *SmartContract) forEachAsset(ctx contractapi.TransactionContextInterface, includeDeleted bool, fn func(asset *Asset) error) error {
+	bookmark := ""
+
+	for {
+		resultsIterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination("", "", rangePageSize, bookmark)
+
+		if err != nil {
+			return fmt.Errorf("failed to read from state: %s", err.Error())
+		}
+
+		assets, err := s.iteratorToAssets(ctx, resultsIterator, includeDeleted)
+
+		resultsIterator.Close()
+
+		if err != nil {
+			return err
+		}
+
+		for _, asset := range assets {
+			if err := fn(asset); err != nil {
+				return err
+			}
+		}
+
+		if metadata == nil || metadata.Bookmark == "" || metadata.FetchedRecordsCount < rangePageSize {
+			return nil
+		}
+
+		bookmark = metadata.Bookmark
+	}
+}
+
+func (s *SmartContract) GetAssetsCreatedBetween(ctx contractapi.TransactionContextInterface, start string, end string) ([]*Asset, error) {
+
+	var err error
+	var startDate time.Time
+	var endDate time.Time
+
+	if startDate, err = s.string2Time(start); err != nil {
+		return nil, err
+	}
+
+	if endDate, err = s.string2Time(end); err != nil {
+		return nil, err
+	}
+
+	if endDate.Before(startDate) {
+		return nil, fmt.Errorf("end must not be before start")
+	}
+
+	assets := []*Asset{}
+
+	err = s.forEachAsset(ctx, false, func(asset *Asset) error {
+		if !asset.CreatedAt.Before(startDate) && !asset.CreatedAt.After(endDate) {
+			assets = append(assets, asset)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return assets, nil
+}
+
+func (s *SmartContract) GetExpiringContracts(ctx contractapi.TransactionContextInterface, withinDays int) ([]*Asset, error) {
+
+	if withinDays < 0 {
+		return nil, fmt.Errorf("within days must not be negative")
+	}
+
+	limit := nowFunc().UTC().Add(time.Duration(withinDays) * time.Duration(timeInSeconds["DAY"]) * time.Second)
+
+	expiring := []*Asset{}
+
+	err := s.forEachAsset(ctx, false, func(asset *Asset) error {
+		if s.contractStatus(asset) == ContractActive && !asset.DueDate.After(limit) {
+			expiring = append(expiring, asset)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return expiring, nil
+}
+
+// QueryAssetsByParty relies on GetQueryResult, so it requires CouchDB as the state database.
+func (s *SmartContract) GetMyAssets(ctx contractapi.TransactionContextInterface) ([]*Asset, error) {
+
+	var id string
+	var err error
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return nil, err
+	}
+
+	if useRichQueries {
+		return s.QueryAssetsByParty(ctx, id)
+	}
+
+	assets := []*Asset{}
+
+	err = s.forEachAsset(ctx, false, func(asset *Asset) error {
+		if asset.Parties.Application.Id == id || asset.Parties.Process.Id == id {
+			assets = append(assets, asset)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return assets, nil
+}
+
+func (s *SmartContract) QueryAssetsByTag(ctx contractapi.TransactionContextInterface, key string, value string) ([]*Asset, error) {
+
+	if key == "" || strings.ContainsAny(key, ".$") {
+		return nil, fmt.Errorf("invalid tag key: %q", key)
+	}
+
+	selector := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"tags." + key: value,
+		},
+	}
+
+	queryString, err := json.Marshal(selector)
+
+	if err != nil {
+		return nil, fmt.Errorf("marshal error: %s", err.Error())
+	}
+
+	resultsIterator, err := ctx.GetStub().GetQueryResult(string(queryString))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query state: %s", err.Error())
+	}
+
+	defer resultsIterator.Close()
+
+	return s.iteratorToAssets(ctx, resultsIterator, false)
+}
+
+func (s *SmartContract) QueryAssetsByParty(ctx contractapi.TransactionContextInterface, partyId string) ([]*Asset, error) {
+
+	selector := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"$or": []map[string]string{
+				{"parties.application.id": partyId},
+				{"parties.process.id": partyId},
+				{"Parties.Application.Id": partyId},
+				{"Parties.Process.Id": partyId},
+			},
+		},
+	}
+
+	queryString, err := json.Marshal(selector)
+
+	if err != nil {
+		return nil, fmt.Errorf("marshal error: %s", err.Error())
+	}
+
+	resultsIterator, err := ctx.GetStub().GetQueryResult(string(queryString))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query state: %s", err.Error())
+	}
+
+	defer resultsIterator.Close()
+
+	return s.iteratorToAssets(ctx, resultsIterator, false)
+}
+
+func (s *SmartContract) GetAssetJSON(ctx contractapi.TransactionContextInterface, assetId string) (string, error) {
+
+	if err := s.checkReadAccess(ctx, assetId); err != nil {
+		return "", err
+	}
+
+	contractAsBytes, err := ctx.GetStub().GetState(assetId)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to read from state: %s", err.Error())
+	}
+
+	if contractAsBytes == nil {
+		return "", fmt.Errorf("%w: %s", ErrAssetNotFound, assetId)
+	}
+
+	return string(contractAsBytes), nil
+}
+
+func (s *SmartContract) AssetExists(ctx contractapi.TransactionContextInterface, assetId string) (bool, error) {
+
+	contractAsBytes, err := ctx.GetStub().GetState(assetId)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to read from state: %s", err.Error())
+	}
+
+	return contractAsBytes != nil, nil
+}
+
+func (s *SmartContract) contractStatus(asset *Asset) string {
+	if asset.Terminated {
+		return ContractTerminated
+	}
+
+	if asset.Cancelled {
+		return ContractCancelled
+	}
+
+	if s.gracePeriodEnd(asset).Before(nowFunc().UTC()) {
+		return ContractExpired
+	}
+
+	if asset.Suspended {
+		return ContractSuspended
+	}
+
+	if asset.IsSigned {
+		return ContractActive
+	}
+
+	if asset.Parties.Application.IsSigned || asset.Parties.Process.IsSigned {
+		return ContractPendingSignatures
+	}
+
+	return ContractDraft
+}
+
+func (s *SmartContract) GetContractStatus(ctx contractapi.TransactionContextInterface, assetId string) (string, error) {
+
+	asset, err := s.QueryAsset(ctx, assetId)
+
+	if err != nil {
+		return "", err
+	}
+
+	return s.contractStatus(asset), nil
+}
+
+func (s *SmartContract) implicitCollection(ctx contractapi.TransactionContextInterface) (string, error) {
+	mspId, err := s.GetClientMSP(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	return "_implicit_org_" + mspId, nil
+}
+
+// PutPartyPrivateData reads the contact from the "contact" transient field and stores it
+// in the caller's implicit organization collection, so the counterparty cannot read it.
+func (s *SmartContract) PutPartyPrivateData(ctx contractapi.TransactionContextInterface, assetId string, role string) error {
+
+	var id string
+	var err error
+	var asset *Asset
+	var party *Party
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return err
+	}
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return err
+	}
+
+	if party, err = s.partyByRole(asset, role); err != nil {
+		return err
+	}
+
+	if party.Id != id {
+		return fmt.Errorf("only the %s can execute this operation", role)
+	}
+
+	transientMap, err := ctx.GetStub().GetTransient()
+
+	if err != nil {
+		return fmt.Errorf("failed to read transient data: %s", err.Error())
+	}
+
+	contactAsBytes, exists := transientMap[contactObjectType]
+
+	if !exists {
+		return fmt.Errorf("contact must be provided in the transient map")
+	}
+
+	contact := new(PartyContact)
+
+	if err = json.Unmarshal(contactAsBytes, contact); err != nil {
+		return fmt.Errorf("marshal error: %s", err.Error())
+	}
+
+	collection, err := s.implicitCollection(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(contactObjectType, []string{assetId, role})
+
+	if err != nil {
+		return fmt.Errorf("failed to create contact key: %s", err.Error())
+	}
+
+	if contactAsBytes, err = json.Marshal(contact); err != nil {
+		return fmt.Errorf("marshal error: %s", err.Error())
+	}
+
+	if err = ctx.GetStub().PutPrivateData(collection, key, contactAsBytes); err != nil {
+		return fmt.Errorf("failed to put private data: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (s *SmartContract) GetPartyPrivateData(ctx contractapi.TransactionContextInterface, assetId string, role string) (*PartyContact, error) {
+
+	collection, err := s.implicitCollection(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(contactObjectType, []string{assetId, role})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create contact key: %s", err.Error())
+	}
+
+	contactAsBytes, err := ctx.GetStub().GetPrivateData(collection, key)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private data: %s", err.Error())
+	}
+
+	if contactAsBytes == nil {
+		return nil, fmt.Errorf("no contact found for %s in asset %s", role, assetId)
+	}
+
+	contact := new(PartyContact)
+
+	if err = json.Unmarshal(contactAsBytes, contact); err != nil {
+		return nil, fmt.Errorf("marshal error: %s", err.Error())
+	}
+
+	return contact, nil
+}
+
+func (s *SmartContract) GetParty(ctx contractapi.TransactionContextInterface, assetId string, role string) (*Party, error) {
+
+	asset, err := s.QueryAsset(ctx, assetId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.partyByRole(asset, role)
+}
+
+func (s *SmartContract) signatureStatus(asset *Asset) []SignatureStatus {
+	return []SignatureStatus{
+		{
+			Role:          RoleApplication,
+			Id:            asset.Parties.Application.Id,
+			IsSigned:      asset.Parties.Application.IsSigned,
+			SignatureDate: asset.Parties.Application.SignatureDate,
+			SignatureRef:  asset.Parties.Application.SignatureRef,
+		},
+		{
+			Role:          RoleProcess,
+			Id:            asset.Parties.Process.Id,
+			IsSigned:      asset.Parties.Process.IsSigned,
+			SignatureDate: asset.Parties.Process.SignatureDate,
+			SignatureRef:  asset.Parties.Process.SignatureRef,
+		},
+	}
+}
+
+func (s *SmartContract) GetSignatureStatus(ctx contractapi.TransactionContextInterface, assetId string) ([]SignatureStatus, error) {
+
+	asset, err := s.QueryAsset(ctx, assetId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.signatureStatus(asset), nil
+}
+
+// GetSignatureStatusBatch reports the signature status of several assets at once. Ids that
+// cannot be read get an entry carrying the error instead of failing the whole call.
+func (s *SmartContract) GetSignatureStatusBatch(ctx contractapi.TransactionContextInterface, assetIds []string) (map[string]SignatureStatusResult, error) {
+
+	results := make(map[string]SignatureStatusResult, len(assetIds))
+
+	for _, assetId := range assetIds {
+		asset, err := s.QueryAsset(ctx, assetId)
+
+		if err != nil {
+			results[assetId] = SignatureStatusResult{Error: err.Error()}
+			continue
+		}
+
+		results[assetId] = SignatureStatusResult{Signatures: s.signatureStatus(asset)}
+	}
+
+	return results, nil
+}
+
+func (s *SmartContract) CountAssetsByStatus(ctx contractapi.TransactionContextInterface) (map[string]int, error) {
+
+	counts := make(map[string]int)
+
+	err := s.forEachAsset(ctx, false, func(asset *Asset) error {
+		counts[s.contractStatus(asset)]++
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+func (s *SmartContract) GetVersion(ctx contractapi.TransactionContextInterface) (string, error) {
+
+	versionAsBytes, err := json.Marshal(VersionInfo{ContractVersion: contractVersion, EngineVersion: engineVersion})
+
+	if err != nil {
+		return "", fmt.Errorf("marshal error: %s", err.Error())
+	}
+
+	return string(versionAsBytes), nil
+}
+
+func (s *SmartContract) GetClientMSP(ctx contractapi.TransactionContextInterface) (string, error) {
+	clientIdentity := ctx.GetClientIdentity()
+
+	if clientIdentity == nil {
+		return "", fmt.Errorf("failed to get client identity")
+	}
+
+	mspId, err := clientIdentity.GetMSPID()
+
+	if err != nil {
+		return "", fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+
+	return mspId, nil
+}
+
+func (s *SmartContract) checkAllowedMSPs(ctx contractapi.TransactionContextInterface, allowedMSPs []string) error {
+	if len(allowedMSPs) == 0 {
+		return nil
+	}
+
+	for _, mspId := range allowedMSPs {
+		fromOrg, err := s.isFromOrg(ctx, mspId)
+
+		if err != nil {
+			return err
+		}
+
+		if fromOrg {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("organization not allowed to execute this clause, expected one of %s", strings.Join(allowedMSPs, "/"))
+}
+
+func (s *SmartContract) isFromOrg(ctx contractapi.TransactionContextInterface, mspId string) (bool, error) {
+	clientMSP, err := s.GetClientMSP(ctx)
+
+	if err != nil {
+		return false, err
+	}
+
+	return clientMSP == mspId, nil
+}
+
+func (s *SmartContract) QueryClientId(ctx contractapi.TransactionContextInterface) (string, error) {
+	clientIdentity := ctx.GetClientIdentity()
+
+	if clientIdentity == nil {
+		return "", fmt.Errorf("failed to get client identity")
+	}
+
+	clientID, err := clientIdentity.GetID()
+
+	if err != nil {
+		return "", fmt.Errorf("failed to get client ID: %v", err)
+	}
+
+	return clientID, nil
+}
+
+func (s *SmartContract) UpdateRequestState(ctx contractapi.TransactionContextInterface, assetId string, requestId string, state string) error {
+
+	var id string
+	var err error
+	var asset *Asset
+
+	if id, err = s.QueryClientId(ctx); err != nil {
+		return err
+	}
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return err
+	}
+
+	if _, err := s.isParty(id, asset); err != nil {
+		return err
+	}
+
+	var request *Request
+
+	if request, err = s.getRequest(ctx, assetId, requestId); err != nil {
+		return err
+	}
+
+	if err := s.canTransitionRequest(request.State, state); err != nil {
+		return err
+	}
+
+	request.State = state
+
+	return s.putRequest(ctx, assetId, request)
+}
+
+func (s *SmartContract) GetRequestsByAsset(ctx contractapi.TransactionContextInterface, assetId string) ([]*Request, error) {
+
+	if err := s.checkReadAccess(ctx, assetId); err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(requestObjectType, []string{assetId})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from state: %s", err.Error())
+	}
+
+	defer resultsIterator.Close()
+
+	requests := []*Request{}
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from state: %s", err.Error())
+		}
+
+		request := new(Request)
+
+		if err = json.Unmarshal(queryResponse.Value, request); err != nil {
+			return nil, fmt.Errorf("marshal error: %s", err.Error())
+		}
+
+		requests = append(requests, request)
+	}
+
+	return requests, nil
+}
+
+func (s *SmartContract) GetAllRequests(ctx contractapi.TransactionContextInterface, assetId string) ([]*Request, error) {
+
+	asset, err := s.QueryAsset(ctx, assetId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.listRequests(ctx, asset)
+}
+
+// listRequests merges the requests stored under their own keys with the legacy ones an older
+// chaincode version kept in asset.Requests, ordered by creation time. Every query that counts
+// or lists requests goes through it, so they all see the same set.
+func (s *SmartContract) listRequests(ctx contractapi.TransactionContextInterface, asset *Asset) ([]*Request, error) {
+	requests, err := s.GetRequestsByAsset(ctx, asset.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+
+	for _, request := range requests {
+		seen[request.Id] = true
+	}
+
+	for id, request := range asset.Requests {
+		if seen[id] {
+			continue
+		}
+
+		legacy := request
+		legacy.Id = id
+		requests = append(requests, &legacy)
+	}
+
+	sort.Slice(requests, func(i, j int) bool {
+		if requests[i].CreatedAt.Equal(requests[j].CreatedAt) {
+			return requests[i].Id < requests[j].Id
+		}
+
+		return requests[i].CreatedAt.Before(requests[j].CreatedAt)
+	})
+
+	return requests, nil
+}
+
+func (s *SmartContract) GetSLACompliance(ctx contractapi.TransactionContextInterface, assetId string) (float64, error) {
+
+	requests, err := s.GetAllRequests(ctx, assetId)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if len(requests) == 0 {
+		return 1.0, nil
+	}
+
+	valid := 0
+
+	for _, request := range requests {
+		if request.Valid {
+			valid++
+		}
+	}
+
+	return float64(valid) / float64(len(requests)), nil
+}
+
+func (s *SmartContract) GetRequestsBetween(ctx contractapi.TransactionContextInterface, assetId string, start string, end string) ([]*Request, error) {
+
+	var startDate time.Time
+	var endDate time.Time
+	var asset *Asset
+	var requests []*Request
+	var err error
+
+	if startDate, err = s.string2Time(start); err != nil {
+		return nil, err
+	}
+
+	if endDate, err = s.string2Time(end); err != nil {
+		return nil, err
+	}
+
+	if startDate.After(endDate) {
+		return nil, fmt.Errorf("start date greater than end date")
+	}
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return nil, err
+	}
+
+	if requests, err = s.listRequests(ctx, asset); err != nil {
+		return nil, err
+	}
+
+	between := []*Request{}
+
+	for _, request := range requests {
+		if request.CreatedAt.Before(startDate) || request.CreatedAt.After(endDate) {
+			continue
+		}
+
+		between = append(between, request)
+	}
+
+	return between, nil
+}
+
+func (s *SmartContract) validateRightRequestBerthing(asset *Asset, clientId string, args RightRequestBerthingArgs, now time.Time) []string {
+	failedRules := []string{}
+
+	if args.MessageContent02 == "" {
+		failedRules = append(failedRules, "messageContent02")
+	}
+
+	if args.MessageContent12 == "" {
+		failedRules = append(failedRules, "messageContent12")
+	}
+
+	if args.MessageContent22 == "" {
+		failedRules = append(failedRules, "messageContent22")
+	}
+
+	if args.MessageContent32 == "" {
+		failedRules = append(failedRules, "messageContent32")
+	}
+
+	return failedRules
+}
+
+// precheckRightRequestBerthing runs the guards shared by the clause and its dry run.
+func (s *SmartContract) precheckRightRequestBerthing(ctx contractapi.TransactionContextInterface, asset *Asset, usage *ClauseUsage, clientId string, args RightRequestBerthingArgs, now time.Time) error {
+	var err error
+
+	if _, err = s.isParty(clientId, asset); err != nil {
+		return err
+	}
+
+	if err = s.checkAllowedMSPs(ctx, asset.RightRequestBerthing.AllowedMSPs); err != nil {
+		return err
+	}
+
+	if err = s.checkProhibition(asset, clientId, "RightRequestBerthing"); err != nil {
+		return err
+	}
+
+	if err = s.checkDependencies(asset, "RightRequestBerthing"); err != nil {
+		return err
+	}
+
+	if err = s.isWithinClauseWindow(asset.RightRequestBerthing.Window); err != nil {
+		return err
+	}
+
+	if err = s.isWithinBusinessHours(asset.RightRequestBerthing.BusinessHours); err != nil {
+		return err
+	}
+
+	if lastRequestAt, exists := usage.LastRequestAt[clientId]; exists {
+		if now.Before(lastRequestAt.Add(time.Duration(asset.RightRequestBerthing.MinIntervalSeconds) * time.Second)) {
+			return fmt.Errorf("operation too frequent")
+		}
+	}
+
+	return nil
+}
+
+func (s *SmartContract) ValidateRightRequestBerthing(ctx contractapi.TransactionContextInterface, assetId string, args RightRequestBerthingArgs) (*ValidationResult, error) {
+
+	var err error
+	var asset *Asset
+	var usage *ClauseUsage
+	var clientId string
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return nil, err
+	}
+
+	if err = s.canExecuteClause(asset); err != nil {
+		return nil, err
+	}
+
+	if usage, err = s.readClauseUsage(ctx, assetId, "RightRequestBerthing"); err != nil {
+		return nil, err
+	}
+
+	if clientId, err = s.QueryClientId(ctx); err != nil {
+		return nil, err
+	}
+
+	now := nowFunc().UTC()
+
+	if err = s.precheckRightRequestBerthing(ctx, asset, usage, clientId, args, now); err != nil {
+		return nil, err
+	}
+
+	failedRules := s.validateRightRequestBerthing(asset, clientId, args, now)
+
+	score := s.score(rightRequestBerthingRules, asset.RightRequestBerthing.RuleWeights, failedRules)
+
+	return &ValidationResult{Valid: score >= asset.RightRequestBerthing.ScoreThreshold, FailedRules: failedRules, Score: score, InGracePeriod: s.isInGracePeriod(asset)}, nil
+}
+
+func (s *SmartContract) ClauseRightRequestBerthing(ctx contractapi.TransactionContextInterface, assetId string, args RightRequestBerthingArgs) (*ValidationResult, error) {
+
+	logger.Infof("RightRequestBerthing: executing for asset %s", assetId)
+
+	result, err := s.clauseRightRequestBerthing(ctx, assetId, args)
+
+	if err != nil {
+		logger.Errorf("RightRequestBerthing: asset %s: %s", assetId, err.Error())
+		return nil, err
+	}
+
+	logger.Infof("RightRequestBerthing: asset %s request %s valid=%t failedRules=%v", assetId, result.RequestId, result.Valid, result.FailedRules)
+
+	return result, nil
+}
+
+func (s *SmartContract) clauseRightRequestBerthing(ctx contractapi.TransactionContextInterface, assetId string, args RightRequestBerthingArgs) (*ValidationResult, error) {
+
+	var err error
+	var asset *Asset
+	var usage *ClauseUsage
+	var rejected *ValidationResult
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return nil, err
+	}
+
+	if rejected, err = s.canExecuteClauseOrNotify(ctx, assetId, asset); rejected != nil || err != nil {
+		return rejected, err
+	}
+
+	if usage, err = s.readClauseUsage(ctx, assetId, "RightRequestBerthing"); err != nil {
+		return nil, err
+	}
+
+	var clientId string
+
+	if clientId, err = s.QueryClientId(ctx); err != nil {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	createdAt := nowFunc().UTC()
+
+	if args.ClientRequestId != "" {
+		var previous *Request
+
+		if previous, err = s.readRequest(ctx, assetId, args.ClientRequestId); err != nil {
+			return nil, err
+		}
+
+		if previous != nil && previous.ClientId != clientId {
+			return nil, fmt.Errorf("client request id %s is already used by another client", args.ClientRequestId)
+		}
+
+		if previous != nil {
+			return &ValidationResult{Valid: previous.Valid, FailedRules: previous.FailedRules, RequestId: previous.Id, Score: previous.Score}, nil
+		}
+
+		id = args.ClientRequestId
+	}
+
+	if err = s.precheckRightRequestBerthing(ctx, asset, usage, clientId, args, createdAt); err != nil {
+		return nil, err
+	}
+
+	failedRules := s.validateRightRequestBerthing(asset, clientId, args, createdAt)
+
+	score := s.score(rightRequestBerthingRules, asset.RightRequestBerthing.RuleWeights, failedRules)
+
+	isValid := score >= asset.RightRequestBerthing.ScoreThreshold
+
+	usage.Used++
+	usage.LastRequestAt[clientId] = createdAt
+
+	if err = s.putClauseUsage(ctx, assetId, "RightRequestBerthing", usage); err != nil {
+		return nil, err
+	}
+
+	if isValid && !s.clauseHasFired(asset, "RightRequestBerthing") {
+		s.markClauseFired(asset, "RightRequestBerthing")
+
+		if err = s.putState(ctx, assetId, asset); err != nil {
+			return nil, err
+		}
+	}
+
+	failureReason := ""
+
+	if !isValid {
+		failureReason = fmt.Sprintf("%s: %s", "Missing required data.", strings.Join(failedRules, ", "))
+	}
+
+	var argsAsBytes []byte
+
+	if argsAsBytes, err = json.Marshal(args); err != nil {
+		return nil, fmt.Errorf("marshal error: %s", err.Error())
+	}
+
+	request := Request{
+		Id:            id,
+		Clause:        "RightRequestBerthing",
+		ClientId:      clientId,
+		CreatedAt:     createdAt,
+		State:         RequestPending,
+		Valid:         isValid,
+		FailedRules:   failedRules,
+		FailureReason: failureReason,
+		Score:         score,
+		Args:          argsAsBytes,
+	}
+
+	if err = s.putRequest(ctx, assetId, &request); err != nil {
+		return nil, err
+	}
+
+	return &ValidationResult{Valid: isValid, FailedRules: failedRules, RequestId: id, Score: score, InGracePeriod: s.isInGracePeriod(asset)}, nil
+}
+
+func (s *SmartContract) validateObligationRespondToPortProposal(asset *Asset, clientId string, args ObligationRespondToPortProposalArgs, request *Request, now time.Time) []string {
+	failedRules := []string{}
+
+	if now.After(request.CreatedAt.Add(time.Duration(asset.ObligationRespondToPortProposal.ObligationRespondToPortProposalTimeout0.Increase) * time.Second)) {
+		failedRules = append(failedRules, "timeout")
+	}
+
+	if args.MessageContent12 == "" {
+		failedRules = append(failedRules, "messageContent12")
+	}
+
+	if args.MessageContent22 == "" {
+		failedRules = append(failedRules, "messageContent22")
+	}
+
+	return failedRules
+}
+
+// precheckObligationRespondToPortProposal runs the guards shared by the clause and its dry run.
+func (s *SmartContract) precheckObligationRespondToPortProposal(ctx contractapi.TransactionContextInterface, asset *Asset, usage *ClauseUsage, clientId string, args ObligationRespondToPortProposalArgs, now time.Time) error {
+	var err error
+
+	if _, err = s.isParty(clientId, asset); err != nil {
+		return err
+	}
+
+	if err = s.checkAllowedMSPs(ctx, asset.ObligationRespondToPortProposal.AllowedMSPs); err != nil {
+		return err
+	}
+
+	if err = s.checkProhibition(asset, clientId, "ObligationRespondToPortProposal"); err != nil {
+		return err
+	}
+
+	if err = s.checkDependencies(asset, "ObligationRespondToPortProposal"); err != nil {
+		return err
+	}
+
+	if err = s.isWithinClauseWindow(asset.ObligationRespondToPortProposal.Window); err != nil {
+		return err
+	}
+
+	if err = s.isWithinBusinessHours(asset.ObligationRespondToPortProposal.BusinessHours); err != nil {
+		return err
+	}
+
+	if lastRequestAt, exists := usage.LastRequestAt[clientId]; exists {
+		if now.Before(lastRequestAt.Add(time.Duration(asset.ObligationRespondToPortProposal.MinIntervalSeconds) * time.Second)) {
+			return fmt.Errorf("operation too frequent")
+		}
+	}
+
+	return nil
+}
+
+func (s *SmartContract) ValidateObligationRespondToPortProposal(ctx contractapi.TransactionContextInterface, assetId string, args ObligationRespondToPortProposalArgs) (*ValidationResult, error) {
+
+	var err error
+	var asset *Asset
+	var usage *ClauseUsage
+	var clientId string
+	var request *Request
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return nil, err
+	}
+
+	if err = s.canExecuteClause(asset); err != nil {
+		return nil, err
+	}
+
+	if usage, err = s.readClauseUsage(ctx, assetId, "ObligationRespondToPortProposal"); err != nil {
+		return nil, err
+	}
+
+	if clientId, err = s.QueryClientId(ctx); err != nil {
+		return nil, err
+	}
+
+	if request, err = s.getRequest(ctx, assetId, args.RequestId); err != nil {
+		return nil, err
+	}
+
+	now := nowFunc().UTC()
+
+	if err = s.precheckObligationRespondToPortProposal(ctx, asset, usage, clientId, args, now); err != nil {
+		return nil, err
+	}
+
+	failedRules := s.validateObligationRespondToPortProposal(asset, clientId, args, request, now)
+
+	score := s.score(obligationRespondToPortProposalRules, asset.ObligationRespondToPortProposal.RuleWeights, failedRules)
+
+	return &ValidationResult{Valid: score >= asset.ObligationRespondToPortProposal.ScoreThreshold, FailedRules: failedRules, Score: score, InGracePeriod: s.isInGracePeriod(asset)}, nil
+}
+
+func (s *SmartContract) ClauseObligationRespondToPortProposal(ctx contractapi.TransactionContextInterface, assetId string, args ObligationRespondToPortProposalArgs) (*ValidationResult, error) {
+
+	logger.Infof("ObligationRespondToPortProposal: executing for asset %s", assetId)
+
+	result, err := s.clauseObligationRespondToPortProposal(ctx, assetId, args)
+
+	if err != nil {
+		logger.Errorf("ObligationRespondToPortProposal: asset %s: %s", assetId, err.Error())
+		return nil, err
+	}
+
+	logger.Infof("ObligationRespondToPortProposal: asset %s request %s valid=%t failedRules=%v", assetId, result.RequestId, result.Valid, result.FailedRules)
+
+	return result, nil
+}
+
+func (s *SmartContract) clauseObligationRespondToPortProposal(ctx contractapi.TransactionContextInterface, assetId string, args ObligationRespondToPortProposalArgs) (*ValidationResult, error) {
+
+	var err error
+	var asset *Asset
+	var usage *ClauseUsage
+	var rejected *ValidationResult
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return nil, err
+	}
+
+	if rejected, err = s.canExecuteClauseOrNotify(ctx, assetId, asset); rejected != nil || err != nil {
+		return rejected, err
+	}
+
+	if usage, err = s.readClauseUsage(ctx, assetId, "ObligationRespondToPortProposal"); err != nil {
+		return nil, err
+	}
+
+	var clientId string
+
+	if clientId, err = s.QueryClientId(ctx); err != nil {
+		return nil, err
+	}
+
+	var request *Request
+
+	if request, err = s.getRequest(ctx, assetId, args.RequestId); err != nil {
+		return nil, err
+	}
+
+	id := request.Id
+	createdAt := nowFunc().UTC()
+
+	if err = s.precheckObligationRespondToPortProposal(ctx, asset, usage, clientId, args, createdAt); err != nil {
+		return nil, err
+	}
+
+	failedRules := s.validateObligationRespondToPortProposal(asset, clientId, args, request, createdAt)
+
+	score := s.score(obligationRespondToPortProposalRules, asset.ObligationRespondToPortProposal.RuleWeights, failedRules)
+
+	isValid := score >= asset.ObligationRespondToPortProposal.ScoreThreshold
+
+	usage.Used++
+	usage.LastRequestAt[clientId] = createdAt
+
+	if err = s.putClauseUsage(ctx, assetId, "ObligationRespondToPortProposal", usage); err != nil {
+		return nil, err
+	}
+
+	changed := false
+
+	if isValid && !s.clauseHasFired(asset, "ObligationRespondToPortProposal") {
+		s.markClauseFired(asset, "ObligationRespondToPortProposal")
+		changed = true
+	}
+
+	// every call that meets the obligation terms owes the configured discount
+	if isValid && asset.ObligationRespondToPortProposal.DiscountPercentage > 0 {
+		asset.PenaltiesApplied = append(asset.PenaltiesApplied, Penalty{Clause: "ObligationRespondToPortProposal", DiscountBasisPoints: asset.ObligationRespondToPortProposal.DiscountPercentage * 100, CreatedAt: createdAt})
+		changed = true
+	}
+
+	if changed {
+		if err = s.putState(ctx, assetId, asset); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ValidationResult{Valid: isValid, FailedRules: failedRules, RequestId: id, Score: score, InGracePeriod: s.isInGracePeriod(asset)}, nil
+}
+
+func (s *SmartContract) validateProhibitionNotAllowedRequestBerthing(asset *Asset, clientId string, args ProhibitionNotAllowedRequestBerthingArgs, now time.Time) []string {
+	failedRules := []string{}
+
+	return failedRules
+}
+
+// precheckProhibitionNotAllowedRequestBerthing runs the guards shared by the clause and its dry run.
+func (s *SmartContract) precheckProhibitionNotAllowedRequestBerthing(ctx contractapi.TransactionContextInterface, asset *Asset, usage *ClauseUsage, clientId string, args ProhibitionNotAllowedRequestBerthingArgs, now time.Time) error {
+	var err error
+
+	if _, err = s.isParty(clientId, asset); err != nil {
+		return err
+	}
+
+	if err = s.checkAllowedMSPs(ctx, asset.ProhibitionNotAllowedRequestBerthing.AllowedMSPs); err != nil {
+		return err
+	}
+
+	if err = s.checkProhibition(asset, clientId, "ProhibitionNotAllowedRequestBerthing"); err != nil {
+		return err
+	}
+
+	if err = s.checkDependencies(asset, "ProhibitionNotAllowedRequestBerthing"); err != nil {
+		return err
+	}
+
+	if err = s.isWithinClauseWindow(asset.ProhibitionNotAllowedRequestBerthing.Window); err != nil {
+		return err
+	}
+
+	if err = s.isWithinBusinessHours(asset.ProhibitionNotAllowedRequestBerthing.BusinessHours); err != nil {
+		return err
+	}
+
+	if lastRequestAt, exists := usage.LastRequestAt[clientId]; exists {
+		if now.Before(lastRequestAt.Add(time.Duration(asset.ProhibitionNotAllowedRequestBerthing.MinIntervalSeconds) * time.Second)) {
+			return fmt.Errorf("operation too frequent")
+		}
+	}
+
+	return nil
+}
+
+func (s *SmartContract) ValidateProhibitionNotAllowedRequestBerthing(ctx contractapi.TransactionContextInterface, assetId string, args ProhibitionNotAllowedRequestBerthingArgs) (*ValidationResult, error) {
+
+	var err error
+	var asset *Asset
+	var usage *ClauseUsage
+	var clientId string
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return nil, err
+	}
+
+	if err = s.canExecuteClause(asset); err != nil {
+		return nil, err
+	}
+
+	if usage, err = s.readClauseUsage(ctx, assetId, "ProhibitionNotAllowedRequestBerthing"); err != nil {
+		return nil, err
+	}
+
+	if clientId, err = s.QueryClientId(ctx); err != nil {
+		return nil, err
+	}
+
+	now := nowFunc().UTC()
+
+	if err = s.precheckProhibitionNotAllowedRequestBerthing(ctx, asset, usage, clientId, args, now); err != nil {
+		return nil, err
+	}
+
+	failedRules := s.validateProhibitionNotAllowedRequestBerthing(asset, clientId, args, now)
+
+	score := s.score(prohibitionNotAllowedRequestBerthingRules, asset.ProhibitionNotAllowedRequestBerthing.RuleWeights, failedRules)
+
+	return &ValidationResult{Valid: score >= asset.ProhibitionNotAllowedRequestBerthing.ScoreThreshold, FailedRules: failedRules, Score: score, InGracePeriod: s.isInGracePeriod(asset)}, nil
+}
+
+func (s *SmartContract) ClauseProhibitionNotAllowedRequestBerthing(ctx contractapi.TransactionContextInterface, assetId string, args ProhibitionNotAllowedRequestBerthingArgs) (*ValidationResult, error) {
+
+	logger.Infof("ProhibitionNotAllowedRequestBerthing: executing for asset %s", assetId)
+
+	result, err := s.clauseProhibitionNotAllowedRequestBerthing(ctx, assetId, args)
+
+	if err != nil {
+		logger.Errorf("ProhibitionNotAllowedRequestBerthing: asset %s: %s", assetId, err.Error())
+		return nil, err
+	}
+
+	logger.Infof("ProhibitionNotAllowedRequestBerthing: asset %s request %s valid=%t failedRules=%v", assetId, result.RequestId, result.Valid, result.FailedRules)
+
+	return result, nil
+}
+
+func (s *SmartContract) clauseProhibitionNotAllowedRequestBerthing(ctx contractapi.TransactionContextInterface, assetId string, args ProhibitionNotAllowedRequestBerthingArgs) (*ValidationResult, error) {
+
+	var err error
+	var asset *Asset
+	var usage *ClauseUsage
+	var rejected *ValidationResult
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return nil, err
+	}
+
+	if rejected, err = s.canExecuteClauseOrNotify(ctx, assetId, asset); rejected != nil || err != nil {
+		return rejected, err
+	}
+
+	if usage, err = s.readClauseUsage(ctx, assetId, "ProhibitionNotAllowedRequestBerthing"); err != nil {
+		return nil, err
+	}
+
+	var clientId string
+
+	if clientId, err = s.QueryClientId(ctx); err != nil {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	createdAt := nowFunc().UTC()
+
+	if args.ClientRequestId != "" {
+		var previous *Request
+
+		if previous, err = s.readRequest(ctx, assetId, args.ClientRequestId); err != nil {
+			return nil, err
+		}
+
+		if previous != nil && previous.ClientId != clientId {
+			return nil, fmt.Errorf("client request id %s is already used by another client", args.ClientRequestId)
+		}
+
+		if previous != nil {
+			return &ValidationResult{Valid: previous.Valid, FailedRules: previous.FailedRules, RequestId: previous.Id, Score: previous.Score}, nil
+		}
+
+		id = args.ClientRequestId
+	}
+
+	if err = s.precheckProhibitionNotAllowedRequestBerthing(ctx, asset, usage, clientId, args, createdAt); err != nil {
+		return nil, err
+	}
+
+	failedRules := s.validateProhibitionNotAllowedRequestBerthing(asset, clientId, args, createdAt)
+
+	score := s.score(prohibitionNotAllowedRequestBerthingRules, asset.ProhibitionNotAllowedRequestBerthing.RuleWeights, failedRules)
+
+	isValid := score >= asset.ProhibitionNotAllowedRequestBerthing.ScoreThreshold
+
+	usage.Used++
+	usage.LastRequestAt[clientId] = createdAt
+
+	if err = s.putClauseUsage(ctx, assetId, "ProhibitionNotAllowedRequestBerthing", usage); err != nil {
+		return nil, err
+	}
+
+	if isValid && !s.clauseHasFired(asset, "ProhibitionNotAllowedRequestBerthing") {
+		s.markClauseFired(asset, "ProhibitionNotAllowedRequestBerthing")
+
+		if err = s.putState(ctx, assetId, asset); err != nil {
+			return nil, err
+		}
+	}
+
+	failureReason := ""
+
+	if !isValid {
+		failureReason = fmt.Sprintf("%s: %s", "Request made outside the valid range", strings.Join(failedRules, ", "))
+	}
+
+	var argsAsBytes []byte
+
+	if argsAsBytes, err = json.Marshal(args); err != nil {
+		return nil, fmt.Errorf("marshal error: %s", err.Error())
+	}
+
+	request := Request{
+		Id:            id,
+		Clause:        "ProhibitionNotAllowedRequestBerthing",
+		ClientId:      clientId,
+		CreatedAt:     createdAt,
+		State:         RequestPending,
+		Valid:         isValid,
+		FailedRules:   failedRules,
+		FailureReason: failureReason,
+		Score:         score,
+		Args:          argsAsBytes,
+	}
+
+	if err = s.putRequest(ctx, assetId, &request); err != nil {
+		return nil, err
+	}
+
+	return &ValidationResult{Valid: isValid, FailedRules: failedRules, RequestId: id, Score: score, InGracePeriod: s.isInGracePeriod(asset)}, nil
+}
+
+func (s *SmartContract) validateObligationRespondToBerthingRequest(asset *Asset, clientId string, args ObligationRespondToBerthingRequestArgs, request *Request, now time.Time) []string {
+	failedRules := []string{}
+
+	if now.After(request.CreatedAt.Add(time.Duration(asset.ObligationRespondToBerthingRequest.ObligationRespondToBerthingRequestTimeout0.Increase) * time.Second)) {
+		failedRules = append(failedRules, "timeout")
+	}
+
+	return failedRules
+}
+
+// precheckObligationRespondToBerthingRequest runs the guards shared by the clause and its dry run.
+func (s *SmartContract) precheckObligationRespondToBerthingRequest(ctx contractapi.TransactionContextInterface, asset *Asset, usage *ClauseUsage, clientId string, args ObligationRespondToBerthingRequestArgs, now time.Time) error {
+	var err error
+
+	if _, err = s.isParty(clientId, asset); err != nil {
+		return err
+	}
+
+	if err = s.checkAllowedMSPs(ctx, asset.ObligationRespondToBerthingRequest.AllowedMSPs); err != nil {
+		return err
+	}
+
+	if err = s.checkProhibition(asset, clientId, "ObligationRespondToBerthingRequest"); err != nil {
+		return err
+	}
+
+	if err = s.checkDependencies(asset, "ObligationRespondToBerthingRequest"); err != nil {
+		return err
+	}
+
+	if err = s.isWithinClauseWindow(asset.ObligationRespondToBerthingRequest.Window); err != nil {
+		return err
+	}
+
+	if err = s.isWithinBusinessHours(asset.ObligationRespondToBerthingRequest.BusinessHours); err != nil {
+		return err
+	}
+
+	if lastRequestAt, exists := usage.LastRequestAt[clientId]; exists {
+		if now.Before(lastRequestAt.Add(time.Duration(asset.ObligationRespondToBerthingRequest.MinIntervalSeconds) * time.Second)) {
+			return fmt.Errorf("operation too frequent")
+		}
+	}
+
+	return nil
+}
+
+func (s *SmartContract) ValidateObligationRespondToBerthingRequest(ctx contractapi.TransactionContextInterface, assetId string, args ObligationRespondToBerthingRequestArgs) (*ValidationResult, error) {
+
+	var err error
+	var asset *Asset
+	var usage *ClauseUsage
+	var clientId string
+	var request *Request
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return nil, err
+	}
+
+	if err = s.canExecuteClause(asset); err != nil {
+		return nil, err
+	}
+
+	if usage, err = s.readClauseUsage(ctx, assetId, "ObligationRespondToBerthingRequest"); err != nil {
+		return nil, err
+	}
+
+	if clientId, err = s.QueryClientId(ctx); err != nil {
+		return nil, err
+	}
+
+	if request, err = s.getRequest(ctx, assetId, args.RequestId); err != nil {
+		return nil, err
+	}
+
+	now := nowFunc().UTC()
+
+	if err = s.precheckObligationRespondToBerthingRequest(ctx, asset, usage, clientId, args, now); err != nil {
+		return nil, err
+	}
+
+	failedRules := s.validateObligationRespondToBerthingRequest(asset, clientId, args, request, now)
+
+	score := s.score(obligationRespondToBerthingRequestRules, asset.ObligationRespondToBerthingRequest.RuleWeights, failedRules)
+
+	return &ValidationResult{Valid: score >= asset.ObligationRespondToBerthingRequest.ScoreThreshold, FailedRules: failedRules, Score: score, InGracePeriod: s.isInGracePeriod(asset)}, nil
+}
+
+func (s *SmartContract) ClauseObligationRespondToBerthingRequest(ctx contractapi.TransactionContextInterface, assetId string, args ObligationRespondToBerthingRequestArgs) (*ValidationResult, error) {
+
+	logger.Infof("ObligationRespondToBerthingRequest: executing for asset %s", assetId)
+
+	result, err := s.clauseObligationRespondToBerthingRequest(ctx, assetId, args)
+
+	if err != nil {
+		logger.Errorf("ObligationRespondToBerthingRequest: asset %s: %s", assetId, err.Error())
+		return nil, err
+	}
+
+	logger.Infof("ObligationRespondToBerthingRequest: asset %s request %s valid=%t failedRules=%v", assetId, result.RequestId, result.Valid, result.FailedRules)
+
+	return result, nil
+}
+
+func (s *SmartContract) clauseObligationRespondToBerthingRequest(ctx contractapi.TransactionContextInterface, assetId string, args ObligationRespondToBerthingRequestArgs) (*ValidationResult, error) {
+
+	var err error
+	var asset *Asset
+	var usage *ClauseUsage
+	var rejected *ValidationResult
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return nil, err
+	}
+
+	if rejected, err = s.canExecuteClauseOrNotify(ctx, assetId, asset); rejected != nil || err != nil {
+		return rejected, err
 	}
 
-	if asset.BeginDate.After(time.Now()) {
-		return fmt.Errorf("the current date is before the start date")
+	if usage, err = s.readClauseUsage(ctx, assetId, "ObligationRespondToBerthingRequest"); err != nil {
+		return nil, err
 	}
 
-	return nil
-}
+	var clientId string
 
-func (s *SmartContract) isApplicationIdValid(id string) error {
-	if id == "" {
-		return fmt.Errorf("application id is required")
+	if clientId, err = s.QueryClientId(ctx); err != nil {
+		return nil, err
 	}
 
-	return nil
-}
+	var request *Request
 
-func (s *SmartContract) isProcessIdValid(id string) error {
-	if id == "" {
-		return fmt.Errorf("process id is required")
+	if request, err = s.getRequest(ctx, assetId, args.RequestId); err != nil {
+		return nil, err
 	}
 
-	return nil
-}
+	id := request.Id
+	createdAt := nowFunc().UTC()
 
-func (s *SmartContract) isBeginDateValid(beginDate time.Time) error {
-	if beginDate.IsZero() {
-		return fmt.Errorf("begin date is required")
+	if err = s.precheckObligationRespondToBerthingRequest(ctx, asset, usage, clientId, args, createdAt); err != nil {
+		return nil, err
 	}
 
-	return nil
-}
+	failedRules := s.validateObligationRespondToBerthingRequest(asset, clientId, args, request, createdAt)
 
-func (s *SmartContract) isDueDateValid(dueDate time.Time) error {
-	if dueDate.IsZero() {
-		return fmt.Errorf("due date is required")
-	}
+	score := s.score(obligationRespondToBerthingRequestRules, asset.ObligationRespondToBerthingRequest.RuleWeights, failedRules)
 
-	return nil
-}
+	isValid := score >= asset.ObligationRespondToBerthingRequest.ScoreThreshold
 
-func (s *SmartContract) isDueDateGreaterThanBeginDate(beginDate time.Time, dueDate time.Time) error {
-	if beginDate.After(dueDate) {
-		return fmt.Errorf("begin date greater than due date")
+	usage.Used++
+	usage.LastRequestAt[clientId] = createdAt
+
+	if err = s.putClauseUsage(ctx, assetId, "ObligationRespondToBerthingRequest", usage); err != nil {
+		return nil, err
 	}
 
-	return nil
-}
+	changed := false
 
-func (s *SmartContract) string2Time(date string) (time.Time, error) {
-	parsed, err := time.Parse(time.RFC3339, date)
+	if isValid && !s.clauseHasFired(asset, "ObligationRespondToBerthingRequest") {
+		s.markClauseFired(asset, "ObligationRespondToBerthingRequest")
+		changed = true
+	}
 
-	if err != nil {
-		return time.Time{}, fmt.Errorf("invalid date. Expected format 2006-01-02T15:04:05Z07:00. Recieved: %s", err.Error())
+	// every call that meets the obligation terms owes the configured discount
+	if isValid && asset.ObligationRespondToBerthingRequest.DiscountPercentage > 0 {
+		asset.PenaltiesApplied = append(asset.PenaltiesApplied, Penalty{Clause: "ObligationRespondToBerthingRequest", DiscountBasisPoints: asset.ObligationRespondToBerthingRequest.DiscountPercentage * 100, CreatedAt: createdAt})
+		changed = true
 	}
 
-	return parsed, nil
+	if changed {
+		if err = s.putState(ctx, assetId, asset); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ValidationResult{Valid: isValid, FailedRules: failedRules, RequestId: id, Score: score, InGracePeriod: s.isInGracePeriod(asset)}, nil
 }
 
-func (s *SmartContract) putState(ctx contractapi.TransactionContextInterface, assetId string, asset *Asset) error {
-	contractAsBytes, err := json.Marshal(asset)
+// ClauseRequestCancellation cancels a request made by the caller, provided it is still
+// within the cancellation window configured on the clause that recorded it.
+func (s *SmartContract) ClauseRequestCancellation(ctx contractapi.TransactionContextInterface, assetId string, args RequestCancellationArgs) (*ValidationResult, error) {
 
-	if err != nil {
-		return fmt.Errorf("marshal error: %s", err.Error())
+	var err error
+	var asset *Asset
+	var rejected *ValidationResult
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return nil, err
 	}
 
-	ctx.GetStub().PutState(assetId, contractAsBytes)
+	if rejected, err = s.canExecuteClauseOrNotify(ctx, assetId, asset); rejected != nil || err != nil {
+		return rejected, err
+	}
 
-	return nil
-}
+	var clientId string
 
-func (s *SmartContract) Init(ctx contractapi.TransactionContextInterface, assetRequest AssetRequest) (string, error) {
-	var beginDate time.Time
-	var dueDate time.Time
-	var err error
+	if clientId, err = s.QueryClientId(ctx); err != nil {
+		return nil, err
+	}
 
-	if beginDate, err = s.string2Time(assetRequest.BeginDate); err != nil {
-		return "", err
+	if _, err = s.isParty(clientId, asset); err != nil {
+		return nil, err
 	}
 
-	if dueDate, err = s.string2Time(assetRequest.DueDate); err != nil {
-		return "", err
+	var request *Request
+
+	if request, err = s.getRequest(ctx, assetId, args.RequestId); err != nil {
+		return nil, err
 	}
 
-	if err := s.isBeginDateValid(beginDate); err != nil {
-		return "", err
+	if request.ClientId != clientId {
+		return nil, fmt.Errorf("only the requesting party can cancel request %s", request.Id)
 	}
 
-	if err := s.isDueDateValid(dueDate); err != nil {
-		return "", err
+	if request.Cancelled {
+		return nil, fmt.Errorf("request %s is already cancelled", request.Id)
 	}
 
-	if err := s.isDueDateGreaterThanBeginDate(beginDate, dueDate); err != nil {
-		return "", err
+	cancellationWindowSeconds := 0
+
+	switch request.Clause {
+	case "RightRequestBerthing":
+		cancellationWindowSeconds = asset.RightRequestBerthing.CancellationWindowSeconds
+	case "ProhibitionNotAllowedRequestBerthing":
+		cancellationWindowSeconds = asset.ProhibitionNotAllowedRequestBerthing.CancellationWindowSeconds
 	}
 
-	if err := s.isApplicationIdValid(assetRequest.Parties.Process.Id); err != nil {
-		return "", err
+	now := nowFunc().UTC()
+	deadline := request.CreatedAt.Add(time.Duration(cancellationWindowSeconds) * time.Second)
+
+	if now.After(deadline) {
+		return nil, fmt.Errorf("cancellation window for request %s closed at %s", request.Id, deadline.Format(time.RFC3339))
 	}
 
-	if err := s.isProcessIdValid(assetRequest.Parties.Process.Id); err != nil {
-		return "", err
+	request.Cancelled = true
+	request.CancelledAt = now
+
+	if err = s.putRequest(ctx, assetId, request); err != nil {
+		return nil, err
 	}
 
-	asset := Asset{}
-	parties := Parties{}
+	logger.Infof("RequestCancellation: asset %s request %s cancelled", assetId, request.Id)
 
-	asset.BeginDate = beginDate
-	asset.DueDate = dueDate
+	return &ValidationResult{Valid: true, FailedRules: []string{}, RequestId: request.Id, Score: request.Score}, nil
+}
 
-	parties.Application.Id = assetRequest.Parties.Application.Id
-	parties.Application.Name = assetRequest.Parties.Application.Name
-	parties.Application.IsSigned = false
+func (s *SmartContract) decodeArgs(argsJSON string, args interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(argsJSON)))
+	decoder.DisallowUnknownFields()
 
-	parties.Process.Id = assetRequest.Parties.Process.Id
-	parties.Process.Name = assetRequest.Parties.Process.Name
-	parties.Process.IsSigned = false
+	return decoder.Decode(args)
+}
 
-	asset.Parties = parties
-	asset.CreatedAt = time.Now()
-	asset.Requests = make(map[string]Request)
+func (s *SmartContract) ExecuteClause(ctx contractapi.TransactionContextInterface, assetId string, clauseName string, argsJSON string) (*ValidationResult, error) {
+
+	switch clauseName {
+	case "RightRequestBerthing":
+		var args RightRequestBerthingArgs
 
-	assetId := uuid.New().String()
+		if err := s.decodeArgs(argsJSON, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments for clause %s: %s", clauseName, err.Error())
+		}
 
-	s.putState(ctx, assetId, &asset)
+		return s.ClauseRightRequestBerthing(ctx, assetId, args)
+	case "ObligationRespondToPortProposal":
+		var args ObligationRespondToPortProposalArgs
 
-	return assetId, nil
+		if err := s.decodeArgs(argsJSON, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments for clause %s: %s", clauseName, err.Error())
+		}
+
+		return s.ClauseObligationRespondToPortProposal(ctx, assetId, args)
+	case "ProhibitionNotAllowedRequestBerthing":
+		var args ProhibitionNotAllowedRequestBerthingArgs
+
+		if err := s.decodeArgs(argsJSON, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments for clause %s: %s", clauseName, err.Error())
+		}
+
+		return s.ClauseProhibitionNotAllowedRequestBerthing(ctx, assetId, args)
+	case "ObligationRespondToBerthingRequest":
+		var args ObligationRespondToBerthingRequestArgs
+
+		if err := s.decodeArgs(argsJSON, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments for clause %s: %s", clauseName, err.Error())
+		}
+
+		return s.ClauseObligationRespondToBerthingRequest(ctx, assetId, args)
+	case "RequestCancellation":
+		var args RequestCancellationArgs
+
+		if err := s.decodeArgs(argsJSON, &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments for clause %s: %s", clauseName, err.Error())
+		}
+
+		return s.ClauseRequestCancellation(ctx, assetId, args)
+	}
+
+	return nil, fmt.Errorf("unknown clause: %s", clauseName)
 }
 
-func (s *SmartContract) Sign(ctx contractapi.TransactionContextInterface, assetId string) error {
+func (s *SmartContract) FulfillObligation(ctx contractapi.TransactionContextInterface, assetId string, obligationId string) error {
 
 	var id string
 	var err error
 	var asset *Asset
+	var party *Party
 
 	if id, err = s.QueryClientId(ctx); err != nil {
 		return err
@@ -296,256 +4569,470 @@ func (s *SmartContract) Sign(ctx contractapi.TransactionContextInterface, assetI
 		return err
 	}
 
-	if _, err := s.isParty(id, asset); err != nil {
+	if err = s.canExecuteClause(asset); err != nil {
 		return err
 	}
 
-	if err := s.isBetweenBeginDateAndDueDate(asset); err != nil {
+	obligation, exists := asset.Obligations[obligationId]
+
+	if !exists {
+		return fmt.Errorf("no obligation found for %s", obligationId)
+	}
+
+	if party, err = s.partyByRole(asset, obligation.Party); err != nil {
 		return err
 	}
 
-	if asset.Parties.Application.Id == id {
+	if party.Id != id {
+		return fmt.Errorf("only the %s can fulfill this obligation", obligation.Party)
+	}
 
-		if _, err := s.isSigned(asset.Parties.Application); err != nil {
-			return err
-		}
+	if obligation.Fulfilled {
+		return fmt.Errorf("obligation %s is already fulfilled", obligationId)
+	}
 
-		asset.Parties.Application.IsSigned = true
-		asset.Parties.Application.SignatureDate = time.Now()
+	obligation.Fulfilled = true
+	obligation.FulfilledAt = nowFunc().UTC()
+	obligation.Overdue = obligation.Overdue || obligation.FulfilledAt.After(obligation.Deadline)
+
+	asset.Obligations[obligationId] = obligation
+
+	return s.putState(ctx, assetId, asset)
+}
+
+func (s *SmartContract) CheckOverdueObligations(ctx contractapi.TransactionContextInterface, assetId string) ([]Obligation, error) {
+
+	var err error
+	var asset *Asset
+
+	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
+		return nil, err
 	}
 
-	if asset.Parties.Process.Id == id {
+	now := nowFunc().UTC()
+	overdue := []Obligation{}
+	changed := false
 
-		if _, err := s.isSigned(asset.Parties.Process); err != nil {
-			return err
+	for obligationId, obligation := range asset.Obligations {
+		if obligation.Fulfilled || !obligation.Deadline.Before(now) {
+			continue
 		}
 
-		asset.Parties.Process.IsSigned = true
-		asset.Parties.Process.SignatureDate = time.Now()
+		if !obligation.Overdue {
+			obligation.Overdue = true
+			asset.Obligations[obligationId] = obligation
+			changed = true
+		}
+
+		overdue = append(overdue, obligation)
 	}
 
-	asset.IsSigned = asset.Parties.Application.IsSigned && asset.Parties.Process.IsSigned
+	sort.Slice(overdue, func(i, j int) bool {
+		if overdue[i].Deadline.Equal(overdue[j].Deadline) {
+			return overdue[i].Id < overdue[j].Id
+		}
 
-	s.putState(ctx, assetId, asset)
+		return overdue[i].Deadline.Before(overdue[j].Deadline)
+	})
 
-	return nil
+	if changed {
+		if err = s.putState(ctx, assetId, asset); err != nil {
+			return nil, err
+		}
+	}
+
+	return overdue, nil
 }
 
-func (s *SmartContract) QueryAsset(ctx contractapi.TransactionContextInterface, assetId string) (*Asset, error) {
+// maxNumberOfOperation returns the operation limit of a clause, if it declares one.
+func (s *SmartContract) maxNumberOfOperation(asset *Asset, clause string) (MaxNumberOfOperation, bool) {
+	switch clause {
+	}
 
-	contractAsBytes, err := ctx.GetStub().GetState(assetId)
+	return MaxNumberOfOperation{}, false
+}
+
+func (s *SmartContract) GetRemainingOperations(ctx contractapi.TransactionContextInterface, assetId string, clause string) (*RemainingOperations, error) {
+
+	asset, err := s.QueryAsset(ctx, assetId)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to read from state: %s", err.Error())
+		return nil, err
 	}
 
-	if contractAsBytes == nil {
-		return nil, fmt.Errorf("asset %s does not exist", assetId)
-	}
+	maxNumberOfOperation, limited := s.maxNumberOfOperation(asset, clause)
 
-	asset := new(Asset)
+	if !limited {
+		return nil, fmt.Errorf("clause %s has no operation limit", clause)
+	}
 
-	err = json.Unmarshal(contractAsBytes, asset)
+	usage, err := s.readClauseUsage(ctx, assetId, clause)
 
 	if err != nil {
-		return nil, fmt.Errorf("marshal error: %s", err.Error())
+		return nil, err
 	}
 
-	return asset, nil
+	return s.remainingOperations(maxNumberOfOperation, usage), nil
 }
 
-func (s *SmartContract) QueryClientId(ctx contractapi.TransactionContextInterface) (string, error) {
-	clientIdentity := ctx.GetClientIdentity()
+func (s *SmartContract) remainingOperations(maxNumberOfOperation MaxNumberOfOperation, usage *ClauseUsage) *RemainingOperations {
+	if usage.End.Before(nowFunc().UTC()) {
+		return &RemainingOperations{Remaining: 0, WindowExpired: true}
+	}
 
-	if clientIdentity == nil {
-		return "", fmt.Errorf("failed to get client identity")
+	remaining := maxNumberOfOperation.Max - usage.Used
+
+	if remaining < 0 {
+		remaining = 0
 	}
 
-	clientID, err := clientIdentity.GetID()
+	return &RemainingOperations{Remaining: remaining}
+}
+
+func (s *SmartContract) GetClauseConfig(ctx contractapi.TransactionContextInterface, assetId string, clause string) (string, error) {
+
+	asset, err := s.QueryAsset(ctx, assetId)
 
 	if err != nil {
-		return "", fmt.Errorf("failed to get client ID: %v", err)
+		return "", err
 	}
 
-	return clientID, nil
+	var config interface{}
+
+	switch clause {
+	case "RightRequestBerthing":
+		config = RightRequestBerthingLimits{
+			MinIntervalSeconds:        asset.RightRequestBerthing.MinIntervalSeconds,
+			CancellationWindowSeconds: asset.RightRequestBerthing.CancellationWindowSeconds,
+			AllowedMSPs:               asset.RightRequestBerthing.AllowedMSPs,
+		}
+	case "ObligationRespondToPortProposal":
+		config = ObligationRespondToPortProposalLimits{
+			MinIntervalSeconds: asset.ObligationRespondToPortProposal.MinIntervalSeconds,
+			AllowedMSPs:        asset.ObligationRespondToPortProposal.AllowedMSPs,
+		}
+	case "ProhibitionNotAllowedRequestBerthing":
+		config = ProhibitionNotAllowedRequestBerthingLimits{
+			MinIntervalSeconds:        asset.ProhibitionNotAllowedRequestBerthing.MinIntervalSeconds,
+			CancellationWindowSeconds: asset.ProhibitionNotAllowedRequestBerthing.CancellationWindowSeconds,
+			AllowedMSPs:               asset.ProhibitionNotAllowedRequestBerthing.AllowedMSPs,
+		}
+	case "ObligationRespondToBerthingRequest":
+		config = ObligationRespondToBerthingRequestLimits{
+			MinIntervalSeconds: asset.ObligationRespondToBerthingRequest.MinIntervalSeconds,
+			AllowedMSPs:        asset.ObligationRespondToBerthingRequest.AllowedMSPs,
+		}
+	default:
+		return "", fmt.Errorf("unknown clause: %s", clause)
+	}
+
+	configAsBytes, err := json.Marshal(config)
+
+	if err != nil {
+		return "", fmt.Errorf("marshal error: %s", err.Error())
+	}
+
+	return string(configAsBytes), nil
 }
 
-func (s *SmartContract) ClauseRightRequestBerthing(ctx contractapi.TransactionContextInterface, assetId string, args RightRequestBerthingArgs) (string, bool, error) {
+func (s *SmartContract) GetAuditTrail(ctx contractapi.TransactionContextInterface, assetId string) ([]AuditEntry, error) {
 
 	var err error
 	var asset *Asset
-
-	executionId := uuid.New().String()
+	var requests []*Request
 
 	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
-		return executionId, false, err
-	}
-
-	if err = s.isBetweenBeginDateAndDueDate(asset); err != nil {
-		return executionId, false, err
+		return nil, err
 	}
 
-	if err = s.assetIsSigned(asset); err != nil {
-		return executionId, false, err
+	if requests, err = s.listRequests(ctx, asset); err != nil {
+		return nil, err
 	}
 
-	createdAt := time.Now()
+	trail := []AuditEntry{}
 
-	var clientId string
+	historyIterator, err := ctx.GetStub().GetHistoryForKey(assetId)
 
-	if clientId, err = s.QueryClientId(ctx); err != nil {
-		return executionId, false, err
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %s", err.Error())
 	}
 
-	asset.Requests[executionId] = Request{
-		clientId:  clientId,
-		createdAt: createdAt,
-	}
+	defer historyIterator.Close()
 
-	s.putState(ctx, assetId, asset)
+	for historyIterator.HasNext() {
+		modification, err := historyIterator.Next()
 
-	isValid := true
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history: %s", err.Error())
+		}
 
-	isValid = isValid && args.MessageContent02 != ""
+		description := "asset updated"
 
-	isValid = isValid && args.MessageContent12 != ""
+		if modification.IsDelete {
+			description = "asset deleted"
+		}
 
-	isValid = isValid && args.MessageContent22 != ""
+		timestamp := time.Time{}
 
-	isValid = isValid && args.MessageContent32 != ""
+		if modification.Timestamp != nil {
+			timestamp = time.Unix(modification.Timestamp.Seconds, int64(modification.Timestamp.Nanos)).UTC()
+		}
 
-	if !isValid {
-		return executionId, isValid, fmt.Errorf("Missing required data.")
+		trail = append(trail, AuditEntry{Type: "state", Timestamp: timestamp, TxId: modification.TxId, Description: description})
 	}
 
-	return executionId, isValid, nil
-}
-
-func (s *SmartContract) ClauseObligationRespondToPortProposal(ctx contractapi.TransactionContextInterface, assetId string, args ObligationRespondToPortProposalArgs, requestId string) (string, bool, error) {
+	for _, entry := range asset.SignatureLog {
+		trail = append(trail, AuditEntry{Type: "signature", Timestamp: entry.SignedAt, ClientId: entry.ClientId, Description: fmt.Sprintf("signed as %s", entry.Role)})
+	}
 
-	var err error
-	var asset *Asset
+	for _, request := range requests {
+		description := fmt.Sprintf("request %s valid", request.Id)
 
-	executionId := uuid.New().String()
+		if !request.Valid {
+			description = fmt.Sprintf("request %s invalid: %s", request.Id, strings.Join(request.FailedRules, ", "))
+		}
 
-	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
-		return executionId, false, err
+		trail = append(trail, AuditEntry{Type: "clause", Timestamp: request.CreatedAt, ClientId: request.ClientId, Description: description})
 	}
 
-	if err = s.isBetweenBeginDateAndDueDate(asset); err != nil {
-		return executionId, false, err
+	for _, amendment := range asset.PendingAmendments {
+		trail = append(trail, AuditEntry{Type: "amendment", Timestamp: amendment.CreatedAt, Description: fmt.Sprintf("%s proposed with value %s", amendment.Operation, amendment.Value)})
 	}
 
-	if err = s.assetIsSigned(asset); err != nil {
-		return executionId, false, err
-	}
+	sort.SliceStable(trail, func(i, j int) bool {
+		if trail[i].Timestamp.Equal(trail[j].Timestamp) {
+			return trail[i].Description < trail[j].Description
+		}
 
-	request, exists := asset.Requests[requestId]
+		return trail[i].Timestamp.Before(trail[j].Timestamp)
+	})
 
-	if !exists {
-		return "", false, fmt.Errorf("no request found for %s", requestId)
+	return trail, nil
+}
+
+func (s *SmartContract) secondsUntil(t time.Time) int64 {
+	remaining := t.Sub(nowFunc().UTC())
+
+	if remaining < 0 {
+		return 0
 	}
 
-	accessDateTime := request.createdAt
+	return int64(remaining / time.Second)
+}
+
+func (s *SmartContract) TimeUntilActive(ctx contractapi.TransactionContextInterface, assetId string) (int64, error) {
+
+	asset, err := s.QueryAsset(ctx, assetId)
 
-	isValid := true
+	if err != nil {
+		return 0, err
+	}
 
-	isValid = isValid && (accessDateTime.Before(asset.ObligationRespondToPortProposal.ObligationRespondToPortProposalTimeout0.End) || accessDateTime.Equal(asset.ObligationRespondToPortProposal.ObligationRespondToPortProposalTimeout0.End))
+	return s.secondsUntil(asset.BeginDate), nil
+}
 
-	isValid = isValid && args.MessageContent12 != ""
+func (s *SmartContract) TimeUntilExpiry(ctx contractapi.TransactionContextInterface, assetId string) (int64, error) {
 
-	isValid = isValid && args.MessageContent22 != ""
+	asset, err := s.QueryAsset(ctx, assetId)
 
-	if !isValid {
-		return executionId, isValid, fmt.Errorf("Timeout for replying has been exceeded")
+	if err != nil {
+		return 0, err
 	}
 
-	return executionId, isValid, nil
+	return s.secondsUntil(asset.DueDate), nil
 }
 
-func (s *SmartContract) ClauseProhibitionNotAllowedRequestBerthing(ctx contractapi.TransactionContextInterface, assetId string) (string, bool, error) {
+// GetRemainingGrace reports the seconds left in the grace window after DueDate. Before the
+// due date the whole grace period is still available.
+func (s *SmartContract) GetRemainingGrace(ctx contractapi.TransactionContextInterface, assetId string) (int64, error) {
 
-	var err error
-	var asset *Asset
+	asset, err := s.QueryAsset(ctx, assetId)
 
-	executionId := uuid.New().String()
+	if err != nil {
+		return 0, err
+	}
 
-	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
-		return executionId, false, err
+	if !nowFunc().UTC().After(asset.DueDate) {
+		return int64(asset.GracePeriodSeconds), nil
 	}
 
-	if err = s.isBetweenBeginDateAndDueDate(asset); err != nil {
-		return executionId, false, err
+	return s.secondsUntil(s.gracePeriodEnd(asset)), nil
+}
+
+func (s *SmartContract) GetContractDuration(ctx contractapi.TransactionContextInterface, assetId string) (*ContractDuration, error) {
+
+	asset, err := s.QueryAsset(ctx, assetId)
+
+	if err != nil {
+		return nil, err
 	}
 
-	if err = s.assetIsSigned(asset); err != nil {
-		return executionId, false, err
+	seconds := int64(asset.DueDate.Sub(asset.BeginDate) / time.Second)
+
+	duration := &ContractDuration{Seconds: seconds, Breakdown: map[string]int{}}
+
+	parts := []string{}
+	remaining := seconds
+
+	for _, unit := range []string{"MONTH", "WEEK", "DAY", "HOUR", "MINUTE", "SECOND"} {
+		amount := remaining / int64(timeInSeconds[unit])
+
+		if amount == 0 {
+			continue
+		}
+
+		remaining -= amount * int64(timeInSeconds[unit])
+		duration.Breakdown[unit] = int(amount)
+		parts = append(parts, fmt.Sprintf("%d %s", amount, strings.ToLower(unit)))
 	}
 
-	createdAt := time.Now()
+	duration.Text = strings.Join(parts, ", ")
 
-	var clientId string
+	return duration, nil
+}
 
-	if clientId, err = s.QueryClientId(ctx); err != nil {
-		return executionId, false, err
+func (s *SmartContract) jsonSchemaFor(t reflect.Type) map[string]interface{} {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
 	}
 
-	asset.Requests[executionId] = Request{
-		clientId:  clientId,
-		createdAt: createdAt,
+	switch t.Kind() {
+	case reflect.Ptr:
+		return s.jsonSchemaFor(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": s.jsonSchemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.jsonSchemaFor(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		required := []string{}
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := strings.Split(field.Tag.Get("json"), ",")
+
+			if tag[0] == "-" || !field.IsExported() {
+				continue
+			}
+
+			name := tag[0]
+
+			if name == "" {
+				name = field.Name
+			}
+
+			properties[name] = s.jsonSchemaFor(field.Type)
+
+			if !strings.Contains(field.Tag.Get("json"), "omitempty") {
+				required = append(required, name)
+			}
+		}
+
+		schema := map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}
+
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+
+		return schema
 	}
 
-	s.putState(ctx, assetId, asset)
+	return map[string]interface{}{}
+}
 
-	isValid := true
+func (s *SmartContract) GetContractJSONSchema(ctx contractapi.TransactionContextInterface) (string, error) {
+
+	schema := map[string]interface{}{
+		"$schema":      "http://json-schema.org/draft-07/schema#",
+		"assetRequest": s.jsonSchemaFor(reflect.TypeOf(AssetRequest{})),
+		"clauses": map[string]interface{}{
+			"RightRequestBerthing":                 s.jsonSchemaFor(reflect.TypeOf(RightRequestBerthingArgs{})),
+			"ObligationRespondToPortProposal":      s.jsonSchemaFor(reflect.TypeOf(ObligationRespondToPortProposalArgs{})),
+			"ProhibitionNotAllowedRequestBerthing": s.jsonSchemaFor(reflect.TypeOf(ProhibitionNotAllowedRequestBerthingArgs{})),
+			"ObligationRespondToBerthingRequest":   s.jsonSchemaFor(reflect.TypeOf(ObligationRespondToBerthingRequestArgs{})),
+			"RequestCancellation":                  s.jsonSchemaFor(reflect.TypeOf(RequestCancellationArgs{})),
+		},
+	}
 
-	if !isValid {
-		return executionId, isValid, fmt.Errorf("Request made outside the valid range")
+	schemaAsBytes, err := json.Marshal(schema)
+
+	if err != nil {
+		return "", fmt.Errorf("marshal error: %s", err.Error())
 	}
 
-	return executionId, isValid, nil
+	return string(schemaAsBytes), nil
 }
 
-func (s *SmartContract) ClauseObligationRespondToBerthingRequest(ctx contractapi.TransactionContextInterface, assetId string, requestId string) (string, bool, error) {
+func (s *SmartContract) GetContractSummary(ctx contractapi.TransactionContextInterface, assetId string) (*ContractSummary, error) {
 
 	var err error
 	var asset *Asset
-
-	executionId := uuid.New().String()
+	var requests []*Request
 
 	if asset, err = s.QueryAsset(ctx, assetId); err != nil {
-		return executionId, false, err
+		return nil, err
 	}
 
-	if err = s.isBetweenBeginDateAndDueDate(asset); err != nil {
-		return executionId, false, err
+	if requests, err = s.listRequests(ctx, asset); err != nil {
+		return nil, err
 	}
 
-	if err = s.assetIsSigned(asset); err != nil {
-		return executionId, false, err
-	}
+	remainingOperations := make(map[string]RemainingOperations)
 
-	request, exists := asset.Requests[requestId]
+	for _, descriptor := range clauseDescriptors {
+		maxNumberOfOperation, limited := s.maxNumberOfOperation(asset, descriptor.Name)
 
-	if !exists {
-		return "", false, fmt.Errorf("no request found for %s", requestId)
-	}
+		if !limited {
+			continue
+		}
+
+		usage, err := s.readClauseUsage(ctx, assetId, descriptor.Name)
 
-	accessDateTime := request.createdAt
+		if err != nil {
+			return nil, err
+		}
 
-	isValid := true
+		remainingOperations[descriptor.Name] = *s.remainingOperations(maxNumberOfOperation, usage)
+	}
 
-	isValid = isValid && (accessDateTime.Before(asset.ObligationRespondToBerthingRequest.ObligationRespondToBerthingRequestTimeout0.End) || accessDateTime.Equal(asset.ObligationRespondToBerthingRequest.ObligationRespondToBerthingRequestTimeout0.End))
+	signedCount := 0
 
-	if !isValid {
-		return executionId, isValid, fmt.Errorf("Timeout for replying has been exceeded")
+	if asset.Parties.Application.IsSigned {
+		signedCount++
+	}
+
+	if asset.Parties.Process.IsSigned {
+		signedCount++
 	}
 
-	return executionId, isValid, nil
+	return &ContractSummary{
+		Id:                  asset.Id,
+		ApplicationName:     asset.Parties.Application.Name,
+		ProcessName:         asset.Parties.Process.Name,
+		Status:              s.contractStatus(asset),
+		SignedCount:         signedCount,
+		RequestCount:        len(requests),
+		RemainingOperations: remainingOperations,
+	}, nil
+}
+
+func (s *SmartContract) ListClauses(ctx contractapi.TransactionContextInterface) ([]ClauseDescriptor, error) {
+	return clauseDescriptors, nil
 }
 
 func main() {
-	chainconde, err := contractapi.NewChaincode(new(SmartContract))
+	contract := new(SmartContract)
+	contract.TransactionContextHandler = new(TransactionContext)
+
+	chainconde, err := contractapi.NewChaincode(contract)
 
 	if err != nil {
 		log.Panicf("error create chaincode: %s", err.Error())
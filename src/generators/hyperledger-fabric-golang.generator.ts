@@ -34,7 +34,7 @@ export class HyperledgerFabricGolangGenerator
       },
       {
         invoke: 'Sign',
-        args: ['<< asset id >>']
+        args: ['<< asset id >>', '<< signature ref >>']
       },
       {
         invoke: 'QueryAsset',
@@ -63,12 +63,18 @@ export class HyperledgerFabricGolangGenerator
         invoke: clause.name.pascal,
         args: [
           '<< asset id >>',
-          clause.variables.reduce<Record<string, string>>((acc, cur) => {
-            acc[cur.name.camel] = '';
-            return acc;
-          }, {})
+          {
+            ...clause.variables.reduce<Record<string, string>>((acc, cur) => {
+              acc[cur.name.camel] = '';
+              return acc;
+            }, {}),
+            ...(clause.terms.some(term => term.type === 'timeout') ? { requestId: '<< request id >>' } : {})
+          }
         ]
-      }))
+      })),
+      ...(data.clauses.some(clause => clause.operation === 'request')
+        ? [{ invoke: 'RequestCancellation', args: ['<< asset id >>', { requestId: '<< request id >>' }] }]
+        : [])
     ];
   }
 
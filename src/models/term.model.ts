@@ -3,4 +3,5 @@ export interface Term {
   variable: string | undefined;
   symbol: string | undefined;
   value: string | undefined;
+  timeUnit?: string;
 }
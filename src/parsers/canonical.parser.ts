@@ -14,6 +14,7 @@ import {
   MessageContentContext,
   TermContext,
   TimeoutContext,
+  MaxNumberOfOperationContext,
   ClauseContext,
   OnBreachContext,
   DatetimeContext,
@@ -108,6 +109,7 @@ export class CanonicalParser {
             const messages = { error: '', success: '' };
 
             let termIndex = 0;
+            let operationIndex = 0;
 
             _clauses.children?.forEach(_clause => {
               if (_clause instanceof OnBreachContext) {
@@ -145,6 +147,30 @@ export class CanonicalParser {
                       });
                     }
 
+                    if (_operation instanceof MaxNumberOfOperationContext) {
+                      const termType = 'maxNumberOfOperation';
+                      const name = {
+                        pascal: `${clauseName.pascal}${capitalizeFirst(termType)}${operationIndex}`,
+                        camel: `${clauseName.camel}${capitalizeFirst(termType)}${operationIndex}`,
+                        snake: `${clauseName.snake}_${termType}_${operationIndex}`
+                      };
+                      const timeUnit =
+                        _operation.Second() ??
+                        _operation.Minute() ??
+                        _operation.Hour() ??
+                        _operation.Day() ??
+                        _operation.Week() ??
+                        _operation.Month();
+
+                      operationIndex++;
+                      terms.push({
+                        name,
+                        type: termType,
+                        value: _operation.digit().text,
+                        timeUnit: timeUnit?.text.toUpperCase() ?? ''
+                      });
+                    }
+
                     if (_operation instanceof MessageContentContext) {
                       const termType = 'messageContent';
 
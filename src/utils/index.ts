@@ -1,3 +1,4 @@
 export * from './capitalize-first.utils';
 export * from './datetime.utils';
 export * from './find-duplicate-words.utils';
+export * from './engine-version.utils';